@@ -854,6 +854,12 @@ pub struct NotesRequest {
     /// Ignored if `asset_id` is unset or if `include_spent` is set.
     #[prost(message, optional, tag = "6")]
     pub amount_to_spend: ::core::option::Option<super::super::core::num::v1::Amount>,
+    /// If set, only populate these fields of each returned `SpendableNoteRecord`.
+    ///
+    /// Paths are top-level field names of `SpendableNoteRecord`, such as `note`
+    /// or `height_created`. If unset, all fields are returned.
+    #[prost(message, optional, tag = "7")]
+    pub field_mask: ::core::option::Option<::pbjson_types::FieldMask>,
 }
 impl ::prost::Name for NotesRequest {
     const NAME: &'static str = "NotesRequest";
@@ -1245,6 +1251,12 @@ pub struct TransactionInfoByHashRequest {
     /// The transaction hash to query for.
     #[prost(message, optional, tag = "2")]
     pub id: ::core::option::Option<super::super::core::txhash::v1::TransactionId>,
+    /// If set, only populate these fields of the returned `TransactionInfo`.
+    ///
+    /// Paths are top-level field names of `TransactionInfo`, such as `height` or
+    /// `view`. If unset, all fields are returned.
+    #[prost(message, optional, tag = "3")]
+    pub field_mask: ::core::option::Option<::pbjson_types::FieldMask>,
 }
 impl ::prost::Name for TransactionInfoByHashRequest {
     const NAME: &'static str = "TransactionInfoByHashRequest";
@@ -1262,6 +1274,12 @@ pub struct TransactionInfoRequest {
     /// If present, return only transactions before this height.
     #[prost(uint64, tag = "2")]
     pub end_height: u64,
+    /// If set, only populate these fields of each returned `TransactionInfo`.
+    ///
+    /// Paths are top-level field names of `TransactionInfo`, such as `height` or
+    /// `view`. If unset, all fields are returned.
+    #[prost(message, optional, tag = "3")]
+    pub field_mask: ::core::option::Option<::pbjson_types::FieldMask>,
 }
 impl ::prost::Name for TransactionInfoRequest {
     const NAME: &'static str = "TransactionInfoRequest";
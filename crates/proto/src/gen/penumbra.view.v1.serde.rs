@@ -3748,6 +3748,9 @@ impl serde::Serialize for NotesRequest {
         if self.amount_to_spend.is_some() {
             len += 1;
         }
+        if self.field_mask.is_some() {
+            len += 1;
+        }
         let mut struct_ser = serializer.serialize_struct("penumbra.view.v1.NotesRequest", len)?;
         if self.include_spent {
             struct_ser.serialize_field("includeSpent", &self.include_spent)?;
@@ -3761,6 +3764,9 @@ impl serde::Serialize for NotesRequest {
         if let Some(v) = self.amount_to_spend.as_ref() {
             struct_ser.serialize_field("amountToSpend", v)?;
         }
+        if let Some(v) = self.field_mask.as_ref() {
+            struct_ser.serialize_field("fieldMask", v)?;
+        }
         struct_ser.end()
     }
 }
@@ -3779,6 +3785,8 @@ impl<'de> serde::Deserialize<'de> for NotesRequest {
             "addressIndex",
             "amount_to_spend",
             "amountToSpend",
+            "field_mask",
+            "fieldMask",
         ];
 
         #[allow(clippy::enum_variant_names)]
@@ -3787,6 +3795,7 @@ impl<'de> serde::Deserialize<'de> for NotesRequest {
             AssetId,
             AddressIndex,
             AmountToSpend,
+            FieldMask,
             __SkipField__,
         }
         impl<'de> serde::Deserialize<'de> for GeneratedField {
@@ -3813,6 +3822,7 @@ impl<'de> serde::Deserialize<'de> for NotesRequest {
                             "assetId" | "asset_id" => Ok(GeneratedField::AssetId),
                             "addressIndex" | "address_index" => Ok(GeneratedField::AddressIndex),
                             "amountToSpend" | "amount_to_spend" => Ok(GeneratedField::AmountToSpend),
+                            "fieldMask" | "field_mask" => Ok(GeneratedField::FieldMask),
                             _ => Ok(GeneratedField::__SkipField__),
                         }
                     }
@@ -3836,6 +3846,7 @@ impl<'de> serde::Deserialize<'de> for NotesRequest {
                 let mut asset_id__ = None;
                 let mut address_index__ = None;
                 let mut amount_to_spend__ = None;
+                let mut field_mask__ = None;
                 while let Some(k) = map_.next_key()? {
                     match k {
                         GeneratedField::IncludeSpent => {
@@ -3862,6 +3873,12 @@ impl<'de> serde::Deserialize<'de> for NotesRequest {
                             }
                             amount_to_spend__ = map_.next_value()?;
                         }
+                        GeneratedField::FieldMask => {
+                            if field_mask__.is_some() {
+                                return Err(serde::de::Error::duplicate_field("fieldMask"));
+                            }
+                            field_mask__ = map_.next_value()?;
+                        }
                         GeneratedField::__SkipField__ => {
                             let _ = map_.next_value::<serde::de::IgnoredAny>()?;
                         }
@@ -3872,6 +3889,7 @@ impl<'de> serde::Deserialize<'de> for NotesRequest {
                     asset_id: asset_id__,
                     address_index: address_index__,
                     amount_to_spend: amount_to_spend__,
+                    field_mask: field_mask__,
                 })
             }
         }
@@ -5652,10 +5670,16 @@ impl serde::Serialize for TransactionInfoByHashRequest {
         if self.id.is_some() {
             len += 1;
         }
+        if self.field_mask.is_some() {
+            len += 1;
+        }
         let mut struct_ser = serializer.serialize_struct("penumbra.view.v1.TransactionInfoByHashRequest", len)?;
         if let Some(v) = self.id.as_ref() {
             struct_ser.serialize_field("id", v)?;
         }
+        if let Some(v) = self.field_mask.as_ref() {
+            struct_ser.serialize_field("fieldMask", v)?;
+        }
         struct_ser.end()
     }
 }
@@ -5667,11 +5691,14 @@ impl<'de> serde::Deserialize<'de> for TransactionInfoByHashRequest {
     {
         const FIELDS: &[&str] = &[
             "id",
+            "field_mask",
+            "fieldMask",
         ];
 
         #[allow(clippy::enum_variant_names)]
         enum GeneratedField {
             Id,
+            FieldMask,
             __SkipField__,
         }
         impl<'de> serde::Deserialize<'de> for GeneratedField {
@@ -5695,6 +5722,7 @@ impl<'de> serde::Deserialize<'de> for TransactionInfoByHashRequest {
                     {
                         match value {
                             "id" => Ok(GeneratedField::Id),
+                            "fieldMask" | "field_mask" => Ok(GeneratedField::FieldMask),
                             _ => Ok(GeneratedField::__SkipField__),
                         }
                     }
@@ -5715,6 +5743,7 @@ impl<'de> serde::Deserialize<'de> for TransactionInfoByHashRequest {
                     V: serde::de::MapAccess<'de>,
             {
                 let mut id__ = None;
+                let mut field_mask__ = None;
                 while let Some(k) = map_.next_key()? {
                     match k {
                         GeneratedField::Id => {
@@ -5723,6 +5752,12 @@ impl<'de> serde::Deserialize<'de> for TransactionInfoByHashRequest {
                             }
                             id__ = map_.next_value()?;
                         }
+                        GeneratedField::FieldMask => {
+                            if field_mask__.is_some() {
+                                return Err(serde::de::Error::duplicate_field("fieldMask"));
+                            }
+                            field_mask__ = map_.next_value()?;
+                        }
                         GeneratedField::__SkipField__ => {
                             let _ = map_.next_value::<serde::de::IgnoredAny>()?;
                         }
@@ -5730,6 +5765,7 @@ impl<'de> serde::Deserialize<'de> for TransactionInfoByHashRequest {
                 }
                 Ok(TransactionInfoByHashRequest {
                     id: id__,
+                    field_mask: field_mask__,
                 })
             }
         }
@@ -5846,6 +5882,9 @@ impl serde::Serialize for TransactionInfoRequest {
         if self.end_height != 0 {
             len += 1;
         }
+        if self.field_mask.is_some() {
+            len += 1;
+        }
         let mut struct_ser = serializer.serialize_struct("penumbra.view.v1.TransactionInfoRequest", len)?;
         if self.start_height != 0 {
             #[allow(clippy::needless_borrow)]
@@ -5855,6 +5894,9 @@ impl serde::Serialize for TransactionInfoRequest {
             #[allow(clippy::needless_borrow)]
             struct_ser.serialize_field("endHeight", ToString::to_string(&self.end_height).as_str())?;
         }
+        if let Some(v) = self.field_mask.as_ref() {
+            struct_ser.serialize_field("fieldMask", v)?;
+        }
         struct_ser.end()
     }
 }
@@ -5869,12 +5911,15 @@ impl<'de> serde::Deserialize<'de> for TransactionInfoRequest {
             "startHeight",
             "end_height",
             "endHeight",
+            "field_mask",
+            "fieldMask",
         ];
 
         #[allow(clippy::enum_variant_names)]
         enum GeneratedField {
             StartHeight,
             EndHeight,
+            FieldMask,
             __SkipField__,
         }
         impl<'de> serde::Deserialize<'de> for GeneratedField {
@@ -5899,6 +5944,7 @@ impl<'de> serde::Deserialize<'de> for TransactionInfoRequest {
                         match value {
                             "startHeight" | "start_height" => Ok(GeneratedField::StartHeight),
                             "endHeight" | "end_height" => Ok(GeneratedField::EndHeight),
+                            "fieldMask" | "field_mask" => Ok(GeneratedField::FieldMask),
                             _ => Ok(GeneratedField::__SkipField__),
                         }
                     }
@@ -5920,6 +5966,7 @@ impl<'de> serde::Deserialize<'de> for TransactionInfoRequest {
             {
                 let mut start_height__ = None;
                 let mut end_height__ = None;
+                let mut field_mask__ = None;
                 while let Some(k) = map_.next_key()? {
                     match k {
                         GeneratedField::StartHeight => {
@@ -5938,6 +5985,12 @@ impl<'de> serde::Deserialize<'de> for TransactionInfoRequest {
                                 Some(map_.next_value::<::pbjson::private::NumberDeserialize<_>>()?.0)
                             ;
                         }
+                        GeneratedField::FieldMask => {
+                            if field_mask__.is_some() {
+                                return Err(serde::de::Error::duplicate_field("fieldMask"));
+                            }
+                            field_mask__ = map_.next_value()?;
+                        }
                         GeneratedField::__SkipField__ => {
                             let _ = map_.next_value::<serde::de::IgnoredAny>()?;
                         }
@@ -5946,6 +5999,7 @@ impl<'de> serde::Deserialize<'de> for TransactionInfoRequest {
                 Ok(TransactionInfoRequest {
                     start_height: start_height__.unwrap_or_default(),
                     end_height: end_height__.unwrap_or_default(),
+                    field_mask: field_mask__,
                 })
             }
         }
@@ -738,6 +738,7 @@ where
                 &mut self2,
                 tonic::Request::new(pb::TransactionInfoByHashRequest {
                     id: Some(id.into()),
+                    field_mask: None,
                 }),
             )
             .await?
@@ -790,6 +791,7 @@ where
             let rsp = self2.transaction_info(tonic::Request::new(pb::TransactionInfoRequest {
                 start_height: start_h,
                 end_height: end_h,
+                field_mask: None,
             }));
             let pb_txs: Vec<_> = rsp.await?.into_inner().try_collect().await?;
 
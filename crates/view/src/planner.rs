@@ -567,6 +567,7 @@ impl<R: RngCore + CryptoRng> Planner<R> {
                     asset_id: Some(required.asset_id.into()),
                     address_index: Some(source.into()),
                     amount_to_spend: None,
+                    field_mask: None,
                 })
                 .await?;
             notes_by_asset_id.insert(
@@ -859,6 +859,10 @@ impl ViewService for ViewServer {
         self.check_worker().await?;
 
         let request = request.into_inner();
+        let field_mask = request.field_mask.clone();
+        if let Some(mask) = &field_mask {
+            check_field_mask("TransactionInfo", TRANSACTION_INFO_FIELDS, &mask.paths)?;
+        }
 
         let fvk =
             self.storage.full_viewing_key().await.map_err(|_| {
@@ -1022,13 +1026,18 @@ impl ViewService for ViewServer {
         // Finally, compute the full TxV from the full TxP:
         let txv = tx.view_from_perspective(&txp);
 
+        let tx_info = pb::TransactionInfo {
+            height,
+            id: Some(tx.id().into()),
+            perspective: Some(txp.into()),
+            transaction: Some(tx.into()),
+            view: Some(txv.into()),
+        };
+
         let response = pb::TransactionInfoByHashResponse {
-            tx_info: Some(pb::TransactionInfo {
-                height,
-                id: Some(tx.id().into()),
-                perspective: Some(txp.into()),
-                transaction: Some(tx.into()),
-                view: Some(txv.into()),
+            tx_info: Some(match &field_mask {
+                Some(mask) => mask_transaction_info(tx_info, &mask.paths),
+                None => tx_info,
             }),
         };
 
@@ -1298,6 +1307,11 @@ impl ViewService for ViewServer {
             .map_or(Ok(None), |v| v.map(Some))
             .map_err(|_| tonic::Status::invalid_argument("invalid amount to spend"))?;
 
+        let field_mask = request.field_mask;
+        if let Some(mask) = &field_mask {
+            check_field_mask("SpendableNoteRecord", NOTE_RECORD_FIELDS, &mask.paths)?;
+        }
+
         let notes = self
             .storage
             .notes(include_spent, asset_id, address_index, amount_to_spend)
@@ -1306,8 +1320,12 @@ impl ViewService for ViewServer {
 
         let stream = try_stream! {
             for note in notes {
+                let note_record: pb::SpendableNoteRecord = note.into();
                 yield pb::NotesResponse {
-                    note_record: Some(note.into()),
+                    note_record: Some(match &field_mask {
+                        Some(mask) => mask_note_record(note_record, &mask.paths),
+                        None => note_record,
+                    }),
                 }
             }
         };
@@ -1448,6 +1466,10 @@ impl ViewService for ViewServer {
         } else {
             Some(request.get_ref().end_height)
         };
+        let field_mask = request.get_ref().field_mask.clone();
+        if let Some(mask) = &field_mask {
+            check_field_mask("TransactionInfo", TRANSACTION_INFO_FIELDS, &mask.paths)?;
+        }
 
         // Fetch transactions from storage.
         let txs = self
@@ -1462,6 +1484,7 @@ impl ViewService for ViewServer {
 
                 let rsp = self2.transaction_info_by_hash(tonic::Request::new(pb::TransactionInfoByHashRequest {
                     id: Some(tx.2.id().into()),
+                    field_mask: field_mask.clone(),
                 })).await?.into_inner();
 
                 yield pb::TransactionInfoResponse {
@@ -1821,3 +1844,70 @@ impl ViewService for ViewServer {
         unimplemented!("unbonding_tokens_by_address_index currently only implemented on web")
     }
 }
+
+/// Top-level `TransactionInfo` fields accepted in a field mask.
+const TRANSACTION_INFO_FIELDS: &[&str] = &["height", "id", "transaction", "perspective", "view"];
+
+/// Top-level `SpendableNoteRecord` fields accepted in a field mask.
+const NOTE_RECORD_FIELDS: &[&str] = &[
+    "note_commitment",
+    "note",
+    "address_index",
+    "nullifier",
+    "height_created",
+    "height_spent",
+    "position",
+    "source",
+    "return_address",
+];
+
+/// Rejects field mask paths that don't name a top-level field of `message`.
+fn check_field_mask(message: &str, fields: &[&str], paths: &[String]) -> Result<(), Status> {
+    match paths.iter().find(|path| !fields.contains(&path.as_str())) {
+        Some(path) => Err(Status::invalid_argument(format!(
+            "field mask path {path:?} is not a field of {message}"
+        ))),
+        None => Ok(()),
+    }
+}
+
+/// Keeps only the fields of `info` named in `paths`, which must already have
+/// been validated with [`check_field_mask`].
+fn mask_transaction_info(mut info: pb::TransactionInfo, paths: &[String]) -> pb::TransactionInfo {
+    let mut masked = pb::TransactionInfo::default();
+    for path in paths {
+        match path.as_str() {
+            "height" => masked.height = info.height,
+            "id" => masked.id = info.id.take(),
+            "transaction" => masked.transaction = info.transaction.take(),
+            "perspective" => masked.perspective = info.perspective.take(),
+            "view" => masked.view = info.view.take(),
+            _ => {}
+        }
+    }
+    masked
+}
+
+/// Keeps only the fields of `record` named in `paths`, which must already have
+/// been validated with [`check_field_mask`].
+fn mask_note_record(
+    mut record: pb::SpendableNoteRecord,
+    paths: &[String],
+) -> pb::SpendableNoteRecord {
+    let mut masked = pb::SpendableNoteRecord::default();
+    for path in paths {
+        match path.as_str() {
+            "note_commitment" => masked.note_commitment = record.note_commitment.take(),
+            "note" => masked.note = record.note.take(),
+            "address_index" => masked.address_index = record.address_index.take(),
+            "nullifier" => masked.nullifier = record.nullifier.take(),
+            "height_created" => masked.height_created = record.height_created,
+            "height_spent" => masked.height_spent = record.height_spent,
+            "position" => masked.position = record.position,
+            "source" => masked.source = record.source.take(),
+            "return_address" => masked.return_address = record.return_address.take(),
+            _ => {}
+        }
+    }
+    masked
+}
@@ -0,0 +1,199 @@
+// Package bech32m encodes and decodes the Bech32m strings used by Penumbra
+// for addresses, asset IDs and keys, as specified in BIP-350.
+//
+// Unlike BIP-173, no limit is placed on the length of the string: Penumbra
+// addresses are longer than 90 characters.
+package bech32m
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Human-readable prefixes, matching the Rust serializers in
+// crates/proto/src/serializers/bech32str.rs.
+const (
+	AddressPrefix                = "penumbra"
+	AssetIDPrefix                = "passet"
+	FullViewingKeyPrefix         = "penumbrafullviewingkey"
+	SpendKeyPrefix               = "penumbraspendkey"
+	WalletIDPrefix               = "penumbrawalletid"
+	ValidatorIdentityKeyPrefix   = "penumbravalid"
+	ValidatorGovernanceKeyPrefix = "penumbragovern"
+	PositionIDPrefix             = "plpid"
+	AuctionIDPrefix              = "pauctid"
+
+	// CompatAddressPrefix is the prefix of compat addresses, which are
+	// Bech32 (BIP-173) rather than Bech32m, for chains whose IBC clients
+	// only accept Bech32. Unlike the others, it ends in "1", so the
+	// separator follows it: "penumbracompat11…".
+	CompatAddressPrefix = "penumbracompat1"
+)
+
+const (
+	charset  = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+	constant = 0x2bc830a3
+	// bech32Constant is the checksum constant of BIP-173.
+	bech32Constant = 1
+)
+
+var charsetRev = func() [128]int8 {
+	var rev [128]int8
+	for i := range rev {
+		rev[i] = -1
+	}
+	for i, c := range charset {
+		rev[c] = int8(i)
+	}
+	return rev
+}()
+
+// ErrChecksum is returned by Decode when the checksum does not match.
+var ErrChecksum = errors.New("bech32m: invalid checksum")
+
+func polymod(values []byte) uint32 {
+	gen := [5]uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+	chk := uint32(1)
+	for _, v := range values {
+		top := chk >> 25
+		chk = (chk&0x1ffffff)<<5 ^ uint32(v)
+		for i := 0; i < 5; i++ {
+			if (top>>i)&1 == 1 {
+				chk ^= gen[i]
+			}
+		}
+	}
+	return chk
+}
+
+func hrpExpand(hrp string) []byte {
+	out := make([]byte, 0, 2*len(hrp)+1)
+	for i := 0; i < len(hrp); i++ {
+		out = append(out, hrp[i]>>5)
+	}
+	out = append(out, 0)
+	for i := 0; i < len(hrp); i++ {
+		out = append(out, hrp[i]&31)
+	}
+	return out
+}
+
+func checksum(hrp string, data []byte) []byte {
+	values := append(hrpExpand(hrp), data...)
+	values = append(values, 0, 0, 0, 0, 0, 0)
+	mod := polymod(values) ^ constant
+	out := make([]byte, 6)
+	for i := range out {
+		out[i] = byte(mod>>(5*(5-i))) & 31
+	}
+	return out
+}
+
+// convertBits regroups data from fromBits-bit to toBits-bit groups. When
+// decoding (pad false), leftover bits must be zero padding.
+func convertBits(data []byte, fromBits, toBits uint, pad bool) ([]byte, error) {
+	var acc uint32
+	var bits uint
+	maxv := uint32(1)<<toBits - 1
+	out := make([]byte, 0, len(data)*int(fromBits)/int(toBits)+1)
+	for _, v := range data {
+		acc = acc<<fromBits | uint32(v)
+		bits += fromBits
+		for bits >= toBits {
+			bits -= toBits
+			out = append(out, byte(acc>>bits&maxv))
+		}
+	}
+	if pad {
+		if bits > 0 {
+			out = append(out, byte(acc<<(toBits-bits)&maxv))
+		}
+	} else if bits >= fromBits || acc<<(toBits-bits)&maxv != 0 {
+		return nil, errors.New("bech32m: invalid padding")
+	}
+	return out, nil
+}
+
+func validHRP(hrp string) error {
+	if hrp == "" {
+		return errors.New("bech32m: empty prefix")
+	}
+	for i := 0; i < len(hrp); i++ {
+		if hrp[i] < 33 || hrp[i] > 126 || (hrp[i] >= 'A' && hrp[i] <= 'Z') {
+			return fmt.Errorf("bech32m: invalid prefix character %q", hrp[i])
+		}
+	}
+	return nil
+}
+
+// Encode returns the Bech32m encoding of data with the given prefix, which
+// must be lowercase.
+func Encode(hrp string, data []byte) (string, error) {
+	if err := validHRP(hrp); err != nil {
+		return "", err
+	}
+	values, err := convertBits(data, 8, 5, true)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	b.Grow(len(hrp) + 1 + len(values) + 6)
+	b.WriteString(hrp)
+	b.WriteByte('1')
+	for _, v := range append(values, checksum(hrp, values)...) {
+		b.WriteByte(charset[v])
+	}
+	return b.String(), nil
+}
+
+// Decode decodes a Bech32m string, and checks that its prefix is hrp.
+func Decode(hrp, s string) ([]byte, error) {
+	return decodePrefix(hrp, s, constant)
+}
+
+// DecodeBech32 decodes a Bech32 (BIP-173) string, and checks that its
+// prefix is hrp. It is only needed for CompatAddressPrefix.
+func DecodeBech32(hrp, s string) ([]byte, error) {
+	return decodePrefix(hrp, s, bech32Constant)
+}
+
+func decodePrefix(hrp, s string, c uint32) ([]byte, error) {
+	got, values, err := decode(s, c)
+	if err != nil {
+		return nil, err
+	}
+	if got != hrp {
+		return nil, fmt.Errorf("bech32m: prefix is %q, expected %q", got, hrp)
+	}
+	return convertBits(values, 5, 8, false)
+}
+
+// decode returns the prefix and the 5-bit data of s, without the checksum,
+// which must match the checksum constant c.
+func decode(s string, c uint32) (string, []byte, error) {
+	lower := strings.ToLower(s)
+	if lower != s && strings.ToUpper(s) != s {
+		return "", nil, errors.New("bech32m: mixed case")
+	}
+	sep := strings.LastIndexByte(lower, '1')
+	if sep < 1 || sep+7 > len(lower) {
+		return "", nil, errors.New("bech32m: missing separator or checksum")
+	}
+	hrp := lower[:sep]
+	if err := validHRP(hrp); err != nil {
+		return "", nil, err
+	}
+	values := make([]byte, 0, len(lower)-sep-1)
+	for i := sep + 1; i < len(lower); i++ {
+		c := lower[i]
+		if c >= 128 || charsetRev[c] < 0 {
+			return "", nil, fmt.Errorf("bech32m: invalid character %q", c)
+		}
+		values = append(values, byte(charsetRev[c]))
+	}
+	if polymod(append(hrpExpand(hrp), values...)) != c {
+		return "", nil, ErrChecksum
+	}
+	return hrp, values[:len(values)-6], nil
+}
@@ -0,0 +1,152 @@
+package bech32m
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// Valid and invalid Bech32m strings from BIP-350.
+func TestBIP350Vectors(t *testing.T) {
+	valid := []string{
+		"A1LQFN3A",
+		"a1lqfn3a",
+		"abcdef1l7aum6echk45nj3s0wdvt2fg8x9yrzpqzd3ryx",
+		"split1checkupstagehandshakeupstreamerranterredcaperredlc445v",
+		"?1v759aa",
+	}
+	for _, s := range valid {
+		if _, _, err := decode(s, constant); err != nil {
+			t.Errorf("decode(%q): %v", s, err)
+		}
+	}
+
+	invalid := []string{
+		"qyrz8wqd2c9m",
+		"1qyrz8wqd2c9m",
+		"y1b0jsk6g",
+		"lt1igcx5c0",
+		"in1muywd",
+		"mm1crxm3i",
+		"au1s5cgom",
+		"M1VUXWEZ",
+		"16plkw9",
+		"1p2gdwpf",
+		// Valid Bech32 (BIP-173), but not Bech32m.
+		"a12uel5l",
+	}
+	for _, s := range invalid {
+		if _, _, err := decode(s, constant); err == nil {
+			t.Errorf("decode(%q): want error", s)
+		}
+	}
+}
+
+// Valid Bech32 strings from BIP-173, which DecodeBech32 accepts and Decode
+// rejects.
+func TestBIP173Vectors(t *testing.T) {
+	for _, s := range []string{
+		"A12UEL5L",
+		"an83characterlonghumanreadablepartthatcontainsthenumber1andtheexcludedcharactersbio1tt5tgs",
+		"abcdef1qpzry9x8gf2tvdw0s3jn54khce6mua7lmqqqxw",
+		"split1checkupstagehandshakeupstreamerranterredcaperred2y9e3w",
+	} {
+		if _, _, err := decode(s, bech32Constant); err != nil {
+			t.Errorf("decode(%q) as Bech32: %v", s, err)
+		}
+		if _, _, err := decode(s, constant); err == nil {
+			t.Errorf("decode(%q) as Bech32m: want error", s)
+		}
+	}
+	b, err := DecodeBech32("abcdef", "abcdef1qpzry9x8gf2tvdw0s3jn54khce6mua7lmqqqxw")
+	if err != nil || len(b) != 20 || b[1] != 0x44 {
+		t.Errorf("DecodeBech32 = %x, %v", b, err)
+	}
+	if _, err := DecodeBech32(CompatAddressPrefix, "abcdef1qpzry9x8gf2tvdw0s3jn54khce6mua7lmqqqxw"); err == nil {
+		t.Error("DecodeBech32 with the wrong prefix: want error")
+	}
+}
+
+// Addresses taken from the Rust test suite.
+func TestPenumbraAddresses(t *testing.T) {
+	for _, s := range []string{
+		"penumbra147mfall0zr6am5r45qkwht7xqqrdsp50czde7empv7yq2nk3z8yyfh9k9520ddgswkmzar22vhz9dwtuem7uxw0qytfpv7lk3q9dp8ccaw2fn5c838rfackazmgf3ahh09cxmz",
+		"penumbra1rqcd3hfvkvc04c4c9vc0ac87lh4y0z8l28k4xp6d0cnd5jc6f6k0neuzp6zdwtpwyfpswtdzv9jzqtpjn5t6wh96pfx3flq2dhqgc42u7c06kj57dl39w2xm6tg0wh4zc8kjjk",
+	} {
+		b, err := Decode(AddressPrefix, s)
+		if err != nil {
+			t.Fatalf("Decode(%q): %v", s, err)
+		}
+		if len(b) != 80 {
+			t.Fatalf("Decode(%q) = %d bytes, want 80", s, len(b))
+		}
+		got, err := Encode(AddressPrefix, b)
+		if err != nil {
+			t.Fatalf("Encode: %v", err)
+		}
+		if got != s {
+			t.Fatalf("Encode = %q, want %q", got, s)
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	for n := 0; n <= 64; n++ {
+		data := make([]byte, n)
+		for i := range data {
+			data[i] = byte(i*7 + n)
+		}
+		s, err := Encode(AssetIDPrefix, data)
+		if err != nil {
+			t.Fatalf("Encode(%d bytes): %v", n, err)
+		}
+		got, err := Decode(AssetIDPrefix, s)
+		if err != nil {
+			t.Fatalf("Decode(%q): %v", s, err)
+		}
+		if !bytes.Equal(got, data) {
+			t.Fatalf("round trip of %d bytes = %x, want %x", n, got, data)
+		}
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	s, err := Encode(AssetIDPrefix, []byte{1, 2, 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Decode(AddressPrefix, s); err == nil {
+		t.Error("Decode with wrong prefix: want error")
+	}
+	corrupt := s[:len(s)-1] + "q"
+	if s[len(s)-1] == 'q' {
+		corrupt = s[:len(s)-1] + "p"
+	}
+	if _, err := Decode(AssetIDPrefix, corrupt); !errors.Is(err, ErrChecksum) {
+		t.Errorf("Decode with bad checksum: err = %v, want %v", err, ErrChecksum)
+	}
+	if _, err := Encode("Passet", nil); err == nil {
+		t.Error("Encode with uppercase prefix: want error")
+	}
+}
+
+func FuzzDecode(f *testing.F) {
+	f.Add("penumbra147mfall0zr6am5r45qkwht7xqqrdsp50czde7empv7yq2nk3z8yyfh9k9520ddgswkmzar22vhz9dwtuem7uxw0qytfpv7lk3q9dp8ccaw2fn5c838rfackazmgf3ahh09cxmz")
+	f.Add("PENUMBRA1QQQQQQ")
+	f.Add("penumbra1")
+	f.Add("a1lqfn3a")
+	f.Fuzz(func(t *testing.T, s string) {
+		b, err := Decode(AddressPrefix, s)
+		if err != nil {
+			return
+		}
+		got, err := Encode(AddressPrefix, b)
+		if err != nil {
+			t.Fatalf("Encode after Decode(%q): %v", s, err)
+		}
+		if got != strings.ToLower(s) {
+			t.Fatalf("Encode(Decode(%q)) = %q", s, got)
+		}
+	})
+}
@@ -0,0 +1,182 @@
+// Package decodelimit bounds the resources spent decoding messages from
+// untrusted peers.
+//
+// proto.Unmarshal allocates in proportion to its input: a few megabytes of
+// crafted bytes can expand into millions of empty repeated messages, or nest
+// deeply enough to exhaust the stack of a recursive consumer. Limits checks
+// the wire encoding against its bounds before anything is allocated, so an
+// indexer following an untrusted node, or a custodian serving an untrusted
+// client, rejects such payloads up front:
+//
+//	limits := decodelimit.Limits{MaxSize: 4 << 20, MaxDepth: 32, MaxRepeated: 10000}
+//	s := grpc.NewServer(grpc.ForceServerCodec(limits.Codec(vtcodec.Codec{})))
+package decodelimit
+
+import (
+	"errors"
+	"fmt"
+
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// ErrLimit is wrapped by the errors returned when an input exceeds a limit.
+var ErrLimit = errors.New("decodelimit: limit exceeded")
+
+// Limits are the bounds on a decoded message. A zero field means no limit.
+type Limits struct {
+	// MaxSize is the maximum size of the encoded message, in bytes.
+	MaxSize int
+	// MaxDepth is the maximum nesting of messages. The top-level message is
+	// at depth 0, and its message fields at depth 1.
+	MaxDepth int
+	// MaxRepeated is the maximum number of elements of any one repeated or
+	// map field in a single message.
+	MaxRepeated int
+}
+
+// Check reports whether b, an encoding of a message described by md, is
+// within the limits. It does not check that b is otherwise valid.
+func (l Limits) Check(b []byte, md protoreflect.MessageDescriptor) error {
+	if l.MaxSize > 0 && len(b) > l.MaxSize {
+		return fmt.Errorf("%w: %s is %d bytes, more than %d", ErrLimit, md.FullName(), len(b), l.MaxSize)
+	}
+	if l.MaxDepth == 0 && l.MaxRepeated == 0 {
+		return nil
+	}
+	return l.check(b, md, 0)
+}
+
+func (l Limits) check(b []byte, md protoreflect.MessageDescriptor, depth int) error {
+	if l.MaxDepth > 0 && depth > l.MaxDepth {
+		return fmt.Errorf("%w: %s is nested more than %d deep", ErrLimit, md.FullName(), l.MaxDepth)
+	}
+	var counts map[protowire.Number]int
+	fields := md.Fields()
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+		fd := fields.ByNumber(num)
+		if fd == nil || typ != protowire.BytesType {
+			// Unknown fields and scalars are kept as they are on the wire, so
+			// they cost no more than their size.
+			n := protowire.ConsumeFieldValue(num, typ, b)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			if fd != nil && fd.IsList() {
+				if counts == nil {
+					counts = map[protowire.Number]int{}
+				}
+				counts[num]++
+				if err := l.checkCount(fd, counts[num]); err != nil {
+					return err
+				}
+			}
+			b = b[n:]
+			continue
+		}
+		v, n := protowire.ConsumeBytes(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+		if fd.IsList() || fd.IsMap() {
+			if counts == nil {
+				counts = map[protowire.Number]int{}
+			}
+			counts[num] += elements(fd, v)
+			if err := l.checkCount(fd, counts[num]); err != nil {
+				return err
+			}
+		}
+		if fd.Message() != nil {
+			if err := l.check(v, fd.Message(), depth+1); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (l Limits) checkCount(fd protoreflect.FieldDescriptor, n int) error {
+	if l.MaxRepeated > 0 && n > l.MaxRepeated {
+		return fmt.Errorf("%w: %s has more than %d elements", ErrLimit, fd.FullName(), l.MaxRepeated)
+	}
+	return nil
+}
+
+// elements returns the number of elements in one length-delimited record of
+// a repeated field: one, unless it is a packed scalar list.
+func elements(fd protoreflect.FieldDescriptor, v []byte) int {
+	switch fd.Kind() {
+	case protoreflect.MessageKind, protoreflect.GroupKind, protoreflect.StringKind, protoreflect.BytesKind:
+		return 1
+	case protoreflect.Fixed32Kind, protoreflect.Sfixed32Kind, protoreflect.FloatKind:
+		return len(v) / 4
+	case protoreflect.Fixed64Kind, protoreflect.Sfixed64Kind, protoreflect.DoubleKind:
+		return len(v) / 8
+	default:
+		// Varints: every byte without the continuation bit ends one.
+		n := 0
+		for _, c := range v {
+			if c < 0x80 {
+				n++
+			}
+		}
+		return n
+	}
+}
+
+// Unmarshal checks b against the limits, then decodes it into m.
+func (l Limits) Unmarshal(b []byte, m proto.Message) error {
+	if err := l.Check(b, m.ProtoReflect().Descriptor()); err != nil {
+		return err
+	}
+	return proto.Unmarshal(b, m)
+}
+
+// Codec returns a grpc codec that checks each message it decodes against
+// the limits before passing it to base, or to proto.Unmarshal if base is nil.
+// Install it with grpc.ForceServerCodec on servers, or grpc.ForceCodec on
+// clients that read from untrusted servers.
+func (l Limits) Codec(base encoding.Codec) encoding.Codec {
+	return codec{limits: l, base: base}
+}
+
+type codec struct {
+	limits Limits
+	base   encoding.Codec
+}
+
+func (c codec) Name() string { return "proto" }
+
+func (c codec) Marshal(v any) ([]byte, error) {
+	if c.base != nil {
+		return c.base.Marshal(v)
+	}
+	m, ok := v.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("decodelimit: cannot marshal %T: not a proto.Message", v)
+	}
+	return proto.Marshal(m)
+}
+
+func (c codec) Unmarshal(data []byte, v any) error {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return fmt.Errorf("decodelimit: cannot unmarshal into %T: not a proto.Message", v)
+	}
+	if err := c.limits.Check(data, m.ProtoReflect().Descriptor()); err != nil {
+		return err
+	}
+	if c.base != nil {
+		return c.base.Unmarshal(data, v)
+	}
+	return proto.Unmarshal(data, m)
+}
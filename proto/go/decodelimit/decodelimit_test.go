@@ -0,0 +1,98 @@
+package decodelimit
+
+import (
+	"errors"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// nested returns a DescriptorProto whose nested types are depth deep.
+func nested(depth int) *descriptorpb.DescriptorProto {
+	m := &descriptorpb.DescriptorProto{Name: proto.String("leaf")}
+	for i := 0; i < depth; i++ {
+		m = &descriptorpb.DescriptorProto{NestedType: []*descriptorpb.DescriptorProto{m}}
+	}
+	return m
+}
+
+func marshal(t *testing.T, m proto.Message) []byte {
+	t.Helper()
+	b, err := proto.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestDepth(t *testing.T) {
+	l := Limits{MaxDepth: 5}
+	if err := l.Unmarshal(marshal(t, nested(5)), new(descriptorpb.DescriptorProto)); err != nil {
+		t.Errorf("depth 5: %v", err)
+	}
+	if err := l.Unmarshal(marshal(t, nested(6)), new(descriptorpb.DescriptorProto)); !errors.Is(err, ErrLimit) {
+		t.Errorf("depth 6: err = %v, want ErrLimit", err)
+	}
+}
+
+func TestRepeated(t *testing.T) {
+	l := Limits{MaxRepeated: 3}
+	for _, tt := range []struct {
+		name string
+		m    proto.Message
+		ok   bool
+	}{
+		{"messages", &descriptorpb.DescriptorProto{Field: make([]*descriptorpb.FieldDescriptorProto, 3)}, true},
+		{"too many messages", &descriptorpb.DescriptorProto{Field: make([]*descriptorpb.FieldDescriptorProto, 4)}, false},
+		{"packed", &descriptorpb.SourceCodeInfo_Location{Path: []int32{1, 300, 70000}}, true},
+		{"too many packed", &descriptorpb.SourceCodeInfo_Location{Path: []int32{1, 300, 70000, 2}}, false},
+		{"strings", &descriptorpb.FileDescriptorProto{Dependency: []string{"a", "b", "c"}}, true},
+		{"too many strings", &descriptorpb.FileDescriptorProto{Dependency: []string{"a", "b", "c", "d"}}, false},
+		// The limit applies to each message, not their total.
+		{"spread", &descriptorpb.FileDescriptorProto{MessageType: []*descriptorpb.DescriptorProto{
+			{Field: make([]*descriptorpb.FieldDescriptorProto, 3)},
+			{Field: make([]*descriptorpb.FieldDescriptorProto, 3)},
+		}}, true},
+	} {
+		b := marshal(t, tt.m)
+		err := l.Check(b, tt.m.ProtoReflect().Descriptor())
+		if tt.ok && err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+		}
+		if !tt.ok && !errors.Is(err, ErrLimit) {
+			t.Errorf("%s: err = %v, want ErrLimit", tt.name, err)
+		}
+	}
+}
+
+func TestSize(t *testing.T) {
+	b := marshal(t, &descriptorpb.FileDescriptorProto{Name: proto.String("penumbra/core/keys/v1/keys.proto")})
+	if err := (Limits{MaxSize: len(b)}).Unmarshal(b, new(descriptorpb.FileDescriptorProto)); err != nil {
+		t.Errorf("at MaxSize: %v", err)
+	}
+	if err := (Limits{MaxSize: len(b) - 1}).Unmarshal(b, new(descriptorpb.FileDescriptorProto)); !errors.Is(err, ErrLimit) {
+		t.Errorf("over MaxSize: err = %v, want ErrLimit", err)
+	}
+}
+
+func TestCodec(t *testing.T) {
+	c := Limits{MaxDepth: 2}.Codec(nil)
+	if c.Name() != "proto" {
+		t.Errorf("Name = %q", c.Name())
+	}
+	b, err := c.Marshal(nested(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := new(descriptorpb.DescriptorProto)
+	if err := c.Unmarshal(b, got); err != nil || !proto.Equal(got, nested(2)) {
+		t.Errorf("Unmarshal = %v, %v", got, err)
+	}
+	if err := c.Unmarshal(marshal(t, nested(3)), new(descriptorpb.DescriptorProto)); !errors.Is(err, ErrLimit) {
+		t.Errorf("Unmarshal too deep: err = %v, want ErrLimit", err)
+	}
+	if err := c.Unmarshal([]byte{0x0a, 0x05}, new(descriptorpb.DescriptorProto)); err == nil {
+		t.Error("Unmarshal truncated: want error")
+	}
+}
@@ -0,0 +1,107 @@
+// Package descriptors embeds the descriptors of every Penumbra message and
+// decodes messages by type URL, without the generated Go types.
+//
+// Explorers and debugging tools receive payloads wrapped in Any, or raw
+// bytes with a type name from a log; Format renders them as JSON:
+//
+//	s, err := descriptors.Format("/penumbra.core.transaction.v1.TransactionPlan", b)
+//
+// The descriptor set, penumbra.binpb, is built from proto/penumbra by
+// deployments/scripts/protobuf-codegen, and includes the imported cosmos,
+// IBC and well-known types. Source info is excluded, so comments are not
+// available.
+package descriptors
+
+import (
+	_ "embed"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/penumbra-zone/penumbra/proto/go/pjson"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+//go:embed penumbra.binpb
+var raw []byte
+
+// FileDescriptorSet returns a copy of the embedded descriptor set.
+func FileDescriptorSet() *descriptorpb.FileDescriptorSet {
+	set := new(descriptorpb.FileDescriptorSet)
+	if err := proto.Unmarshal(raw, set); err != nil {
+		panic(fmt.Sprintf("descriptors: invalid embedded descriptor set: %v", err))
+	}
+	return set
+}
+
+// Files returns the files in the embedded descriptor set. They are kept
+// apart from protoregistry.GlobalFiles, so they do not conflict with
+// generated packages linked into the same binary.
+var Files = sync.OnceValue(func() *protoregistry.Files {
+	files, err := protodesc.NewFiles(FileDescriptorSet())
+	if err != nil {
+		panic(fmt.Sprintf("descriptors: invalid embedded descriptor set: %v", err))
+	}
+	return files
+})
+
+// Types returns the message, enum and extension types of Files, backed by
+// dynamicpb.
+var Types = sync.OnceValue(func() *dynamicpb.Types {
+	return dynamicpb.NewTypes(Files())
+})
+
+// MessageDescriptor returns the descriptor for a type URL, such as
+// "type.googleapis.com/penumbra.core.keys.v1.Address", or a bare message
+// name.
+func MessageDescriptor(typeURL string) (protoreflect.MessageDescriptor, error) {
+	name := typeURL[strings.LastIndexByte(typeURL, '/')+1:]
+	mt, err := Types().FindMessageByName(protoreflect.FullName(name))
+	if err != nil {
+		return nil, fmt.Errorf("descriptors: %s: %w", name, err)
+	}
+	return mt.Descriptor(), nil
+}
+
+// Decode decodes b as the message named by typeURL.
+func Decode(typeURL string, b []byte) (proto.Message, error) {
+	md, err := MessageDescriptor(typeURL)
+	if err != nil {
+		return nil, err
+	}
+	m := dynamicpb.NewMessage(md)
+	if err := (proto.UnmarshalOptions{Resolver: Types()}).Unmarshal(b, m); err != nil {
+		return nil, fmt.Errorf("descriptors: %s: %w", md.FullName(), err)
+	}
+	return m, nil
+}
+
+// DecodeAny decodes the message packed in a.
+func DecodeAny(a *anypb.Any) (proto.Message, error) {
+	return Decode(a.GetTypeUrl(), a.GetValue())
+}
+
+// Format decodes b as the message named by typeURL and renders it as
+// indented JSON in the pjson form, with addresses and asset IDs as Bech32m
+// strings and amounts as decimals. Any values nested in it are expanded.
+func Format(typeURL string, b []byte) (string, error) {
+	m, err := Decode(typeURL, b)
+	if err != nil {
+		return "", err
+	}
+	out, err := pjson.MarshalOptions{MarshalOptions: protojson.MarshalOptions{
+		Multiline: true,
+		Resolver:  Types(),
+	}}.Marshal(m)
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
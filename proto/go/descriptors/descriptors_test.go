@@ -0,0 +1,125 @@
+package descriptors
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+func TestFiles(t *testing.T) {
+	for _, name := range []string{
+		"penumbra.core.transaction.v1.TransactionPlan",
+		"penumbra.core.component.compact_block.v1.CompactBlock",
+		"penumbra.custody.v1.AuthorizeRequest",
+		"penumbra.view.v1.NotesRequest",
+		"ibc.core.client.v1.Height",
+	} {
+		if _, err := MessageDescriptor(name); err != nil {
+			t.Errorf("MessageDescriptor(%s): %v", name, err)
+		}
+	}
+	md, err := MessageDescriptor("type.googleapis.com/penumbra.view.v1.NotesRequest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if md.Fields().ByName("field_mask") == nil {
+		t.Error("NotesRequest has no field_mask; penumbra.binpb is out of date")
+	}
+	if _, err := MessageDescriptor("penumbra.core.keys.v1.Nope"); err == nil {
+		t.Error("MessageDescriptor of an unknown name: want error")
+	}
+}
+
+// build returns the binary encoding of a message given in protojson.
+func build(t *testing.T, name, s string) []byte {
+	t.Helper()
+	md, err := MessageDescriptor(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := dynamicpb.NewMessage(md)
+	if err := (protojson.UnmarshalOptions{Resolver: Types()}).Unmarshal([]byte(s), m); err != nil {
+		t.Fatalf("protojson.Unmarshal(%s): %v", s, err)
+	}
+	b, err := proto.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestFormat(t *testing.T) {
+	value := build(t, "penumbra.core.asset.v1.Value", `{
+		"amount": {"lo": "1000000"},
+		"assetId": {"inner": "AAECAwQFBgcICQoLDA0ODxAREhMUFRYXGBkaGxwdHh8="}
+	}`)
+	a := &anypb.Any{TypeUrl: "type.googleapis.com/penumbra.core.asset.v1.Value", Value: value}
+	m, err := DecodeAny(a)
+	if err != nil {
+		t.Fatalf("DecodeAny: %v", err)
+	}
+	if got := m.ProtoReflect().Descriptor().FullName(); got != "penumbra.core.asset.v1.Value" {
+		t.Fatalf("DecodeAny = %s", got)
+	}
+
+	got, err := Format(a.TypeUrl, a.Value)
+	if err != nil {
+		t.Fatalf("Format: %v", err)
+	}
+	want := `{
+  "amount": "1000000",
+  "assetId": "passet1qqqsyqcyq5rqwzqfpg9scrgwpugpzysnzs23v9ccrydpk8qarc0s0ur7kn"
+}`
+	if got != want {
+		t.Errorf("Format =\n%s\nwant\n%s", got, want)
+	}
+
+	if _, err := Format("penumbra.core.asset.v1.Value", []byte{0xff}); err == nil {
+		t.Error("Format of invalid bytes: want error")
+	}
+}
+
+// TestUpToDate compares the embedded descriptors of the Penumbra files with
+// a freshly built set, if one is given:
+//
+//	buf build penumbra --exclude-source-info -o /tmp/penumbra.binpb
+//	PENUMBRA_DESCRIPTOR_SET=/tmp/penumbra.binpb go test ./descriptors
+func TestUpToDate(t *testing.T) {
+	path := os.Getenv("PENUMBRA_DESCRIPTOR_SET")
+	if path == "" {
+		t.Skip("PENUMBRA_DESCRIPTOR_SET not set")
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fresh := new(descriptorpb.FileDescriptorSet)
+	if err := proto.Unmarshal(b, fresh); err != nil {
+		t.Fatal(err)
+	}
+	embedded := map[string]*descriptorpb.FileDescriptorProto{}
+	for _, f := range FileDescriptorSet().GetFile() {
+		embedded[f.GetName()] = f
+	}
+	for _, f := range fresh.GetFile() {
+		if !strings.HasPrefix(f.GetName(), "penumbra/") {
+			continue
+		}
+		f.SourceCodeInfo = nil
+		if !proto.Equal(f, embedded[f.GetName()]) {
+			t.Errorf("%s differs; regenerate penumbra.binpb", f.GetName())
+		}
+		delete(embedded, f.GetName())
+	}
+	for name := range embedded {
+		if strings.HasPrefix(name, "penumbra/") {
+			t.Errorf("%s is embedded but no longer exists; regenerate penumbra.binpb", name)
+		}
+	}
+}
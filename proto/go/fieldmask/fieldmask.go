@@ -0,0 +1,44 @@
+// Package fieldmask applies the field masks accepted by the view service's
+// NotesRequest, TransactionInfoRequest and TransactionInfoByHashRequest.
+//
+// As in the Rust view server, mask paths are top-level field names of the
+// response message; nested paths are not supported.
+package fieldmask
+
+import (
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+)
+
+// Check returns an InvalidArgument status error if any path in mask is not a
+// top-level field of md. A nil mask is valid.
+func Check(md protoreflect.MessageDescriptor, mask *fieldmaskpb.FieldMask) error {
+	for _, path := range mask.GetPaths() {
+		if md.Fields().ByName(protoreflect.Name(path)) == nil {
+			return status.Errorf(codes.InvalidArgument, "field mask path %q is not a field of %s", path, md.Name())
+		}
+	}
+	return nil
+}
+
+// Prune clears every field of m not named in mask. A nil mask leaves m
+// unchanged. The mask must already have been validated with Check.
+func Prune(m proto.Message, mask *fieldmaskpb.FieldMask) {
+	if mask == nil {
+		return
+	}
+	keep := make(map[protoreflect.Name]bool, len(mask.GetPaths()))
+	for _, path := range mask.GetPaths() {
+		keep[protoreflect.Name(path)] = true
+	}
+	r := m.ProtoReflect()
+	r.Range(func(fd protoreflect.FieldDescriptor, _ protoreflect.Value) bool {
+		if !keep[fd.Name()] {
+			r.Clear(fd)
+		}
+		return true
+	})
+}
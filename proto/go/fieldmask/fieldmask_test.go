@@ -0,0 +1,50 @@
+package fieldmask
+
+import (
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+)
+
+func TestCheck(t *testing.T) {
+	md := (&descriptorpb.FileDescriptorProto{}).ProtoReflect().Descriptor()
+	if err := Check(md, nil); err != nil {
+		t.Fatalf("Check(nil): %v", err)
+	}
+	if err := Check(md, &fieldmaskpb.FieldMask{Paths: []string{"name", "package"}}); err != nil {
+		t.Fatalf("Check(name, package): %v", err)
+	}
+	for _, path := range []string{"nope", "options.java_package", "messageType"} {
+		err := Check(md, &fieldmaskpb.FieldMask{Paths: []string{"name", path}})
+		if status.Code(err) != codes.InvalidArgument {
+			t.Errorf("Check(%q) = %v, want InvalidArgument", path, err)
+		}
+	}
+}
+
+func TestPrune(t *testing.T) {
+	m := &descriptorpb.FileDescriptorProto{
+		Name:       proto.String("view.proto"),
+		Package:    proto.String("penumbra.view.v1"),
+		Dependency: []string{"google/protobuf/field_mask.proto"},
+		Options:    &descriptorpb.FileOptions{GoPackage: proto.String("viewv1")},
+	}
+	Prune(m, &fieldmaskpb.FieldMask{Paths: []string{"package", "options"}})
+	want := &descriptorpb.FileDescriptorProto{
+		Package: proto.String("penumbra.view.v1"),
+		Options: &descriptorpb.FileOptions{GoPackage: proto.String("viewv1")},
+	}
+	if !proto.Equal(m, want) {
+		t.Fatalf("Prune = %v, want %v", m, want)
+	}
+
+	unmasked := proto.Clone(want)
+	Prune(unmasked, nil)
+	if !proto.Equal(unmasked, want) {
+		t.Fatalf("Prune(nil) = %v, want %v", unmasked, want)
+	}
+}
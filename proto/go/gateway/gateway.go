@@ -0,0 +1,40 @@
+// Package gateway mounts the generated grpc-gateway reverse proxies for the
+// custody and view services on a single HTTP handler.
+//
+// The proxies are generated locally from proto/buf.gen.gateway.yaml; see the
+// "Generating Go code" section of the protobuf guide.
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc"
+)
+
+// RegisterFunc has the signature of the generated
+// Register<Service>HandlerFromEndpoint functions, such as
+// viewv1.RegisterViewServiceHandlerFromEndpoint.
+type RegisterFunc func(ctx context.Context, mux *runtime.ServeMux, endpoint string, opts []grpc.DialOption) error
+
+// NewHandler returns an http.Handler that serves each registered service as
+// REST routes, proxied to the gRPC server at endpoint.
+//
+// The custody service performs no authentication of its own, so its proxy
+// accepts Authorize requests from anyone who can reach the handler. Only
+// register custodyv1.RegisterCustodyServiceHandlerFromEndpoint on a listener
+// that is not exposed to untrusted clients.
+func NewHandler(ctx context.Context, endpoint string, opts []grpc.DialOption, register ...RegisterFunc) (http.Handler, error) {
+	if len(register) == 0 {
+		return nil, fmt.Errorf("gateway: no services to register")
+	}
+	mux := runtime.NewServeMux()
+	for _, r := range register {
+		if err := r(ctx, mux, endpoint, opts); err != nil {
+			return nil, fmt.Errorf("gateway: registering handler for %s: %w", endpoint, err)
+		}
+	}
+	return mux, nil
+}
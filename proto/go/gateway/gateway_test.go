@@ -0,0 +1,48 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc"
+)
+
+func TestNewHandlerRegistersEachService(t *testing.T) {
+	var endpoints []string
+	register := func(ctx context.Context, mux *runtime.ServeMux, endpoint string, opts []grpc.DialOption) error {
+		endpoints = append(endpoints, endpoint)
+		return mux.HandlePath(http.MethodPost, "/penumbra.view.v1.ViewService/Status", func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+			w.WriteHeader(http.StatusNoContent)
+		})
+	}
+
+	h, err := NewHandler(context.Background(), "localhost:8081", nil, register)
+	if err != nil {
+		t.Fatalf("NewHandler: %v", err)
+	}
+	if len(endpoints) != 1 || endpoints[0] != "localhost:8081" {
+		t.Fatalf("register called with %v, want [localhost:8081]", endpoints)
+	}
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/penumbra.view.v1.ViewService/Status", nil))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusNoContent)
+	}
+}
+
+func TestNewHandlerErrors(t *testing.T) {
+	if _, err := NewHandler(context.Background(), "localhost:8081", nil); err == nil {
+		t.Fatal("NewHandler with no services: want error")
+	}
+
+	errDial := errors.New("dial failed")
+	failing := func(context.Context, *runtime.ServeMux, string, []grpc.DialOption) error { return errDial }
+	if _, err := NewHandler(context.Background(), "localhost:8081", nil, failing); !errors.Is(err, errDial) {
+		t.Fatalf("err = %v, want wrapped %v", err, errDial)
+	}
+}
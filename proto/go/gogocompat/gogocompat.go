@@ -0,0 +1,90 @@
+// Package gogocompat exchanges Penumbra messages with code built on
+// gogo/protobuf, such as Cosmos SDK modules and IBC relayers.
+//
+// gogo-generated types cannot be used with google.golang.org/protobuf: they
+// lack protoreflect support, and gogoproto options such as customtype and
+// nullable=false give them different Go shapes. They do share the wire
+// format, so conversion goes through it. The package does not import
+// gogo/protobuf; any type with gogo's generated Marshal and Unmarshal
+// methods works.
+//
+//	var coin banktypes.Coin // gogo
+//	err := gogocompat.ToGogo(&coin, penumbraCoin)
+package gogocompat
+
+import (
+	"fmt"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// Marshaler is implemented by gogo-generated messages.
+type Marshaler interface {
+	Marshal() ([]byte, error)
+}
+
+// Unmarshaler is implemented by pointers to gogo-generated messages.
+type Unmarshaler interface {
+	Unmarshal([]byte) error
+}
+
+// FromGogo sets dst to the message encoded by the gogo message src.
+func FromGogo(dst proto.Message, src Marshaler) error {
+	b, err := src.Marshal()
+	if err != nil {
+		return fmt.Errorf("gogocompat: marshal %T: %w", src, err)
+	}
+	if err := proto.Unmarshal(b, dst); err != nil {
+		return fmt.Errorf("gogocompat: unmarshal %T: %w", dst, err)
+	}
+	return nil
+}
+
+// ToGogo sets the gogo message dst to src.
+func ToGogo(dst Unmarshaler, src proto.Message) error {
+	b, err := proto.Marshal(src)
+	if err != nil {
+		return fmt.Errorf("gogocompat: marshal %T: %w", src, err)
+	}
+	if err := dst.Unmarshal(b); err != nil {
+		return fmt.Errorf("gogocompat: unmarshal %T: %w", dst, err)
+	}
+	return nil
+}
+
+// TypeURL returns the type URL the Cosmos SDK uses for m in Any values:
+// its full name after a slash, without the type.googleapis.com host that
+// anypb.New adds.
+func TypeURL(m proto.Message) string {
+	return "/" + string(m.ProtoReflect().Descriptor().FullName())
+}
+
+// NewAny packs m into an Any with the Cosmos SDK type URL. The result is
+// wire compatible with gogo's codectypes.Any.
+func NewAny(m proto.Message) (*anypb.Any, error) {
+	b, err := proto.Marshal(m)
+	if err != nil {
+		return nil, fmt.Errorf("gogocompat: marshal %T: %w", m, err)
+	}
+	return &anypb.Any{TypeUrl: TypeURL(m), Value: b}, nil
+}
+
+// UnpackAny decodes the message in a, accepting both the Cosmos SDK and the
+// type.googleapis.com forms of its type URL. The type must be linked into
+// the binary.
+func UnpackAny(a *anypb.Any) (proto.Message, error) {
+	url := a.GetTypeUrl()
+	name := url[strings.LastIndexByte(url, '/')+1:]
+	mt, err := protoregistry.GlobalTypes.FindMessageByURL(name)
+	if err != nil {
+		return nil, fmt.Errorf("gogocompat: %s: %w", url, err)
+	}
+	m := mt.New().Interface()
+	if err := proto.Unmarshal(a.GetValue(), m); err != nil {
+		return nil, fmt.Errorf("gogocompat: %s: %w", url, err)
+	}
+	return m, nil
+}
@@ -0,0 +1,90 @@
+package gogocompat
+
+import (
+	"errors"
+	"testing"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// gogoUInt64 mimics a gogo-generated message with hand-written wire
+// methods, like the code gogo's marshaler plugin emits for UInt64Value.
+type gogoUInt64 struct {
+	Value uint64
+}
+
+func (m *gogoUInt64) Marshal() ([]byte, error) {
+	if m.Value == 0 {
+		return nil, nil
+	}
+	b := protowire.AppendTag(nil, 1, protowire.VarintType)
+	return protowire.AppendVarint(b, m.Value), nil
+}
+
+func (m *gogoUInt64) Unmarshal(b []byte) error {
+	*m = gogoUInt64{}
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+		if num == 1 && typ == protowire.VarintType {
+			v, n := protowire.ConsumeVarint(b)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			m.Value, b = v, b[n:]
+			continue
+		}
+		n = protowire.ConsumeFieldValue(num, typ, b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+	}
+	return nil
+}
+
+func TestConvert(t *testing.T) {
+	var got wrapperspb.UInt64Value
+	if err := FromGogo(&got, &gogoUInt64{Value: 1 << 40}); err != nil || got.Value != 1<<40 {
+		t.Errorf("FromGogo = %v, %v", got.Value, err)
+	}
+	var back gogoUInt64
+	if err := ToGogo(&back, wrapperspb.UInt64(7)); err != nil || back.Value != 7 {
+		t.Errorf("ToGogo = %v, %v", back.Value, err)
+	}
+	if err := FromGogo(&got, failing{}); err == nil {
+		t.Error("FromGogo with a failing Marshal: want error")
+	}
+}
+
+type failing struct{}
+
+func (failing) Marshal() ([]byte, error) { return nil, errors.New("no") }
+
+func TestAny(t *testing.T) {
+	a, err := NewAny(wrapperspb.String("penumbra"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.TypeUrl != "/google.protobuf.StringValue" {
+		t.Errorf("TypeUrl = %q", a.TypeUrl)
+	}
+	for _, a := range []*anypb.Any{a, {TypeUrl: "type.googleapis.com/google.protobuf.StringValue", Value: a.Value}} {
+		m, err := UnpackAny(a)
+		if err != nil {
+			t.Fatalf("UnpackAny(%s): %v", a.TypeUrl, err)
+		}
+		if !proto.Equal(m, wrapperspb.String("penumbra")) {
+			t.Errorf("UnpackAny(%s) = %v", a.TypeUrl, m)
+		}
+	}
+	if _, err := UnpackAny(&anypb.Any{TypeUrl: "/penumbra.core.keys.v1.Nope"}); err == nil {
+		t.Error("UnpackAny of an unknown type: want error")
+	}
+}
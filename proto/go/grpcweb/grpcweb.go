@@ -0,0 +1,113 @@
+// Package grpcweb serves connect-go handlers to browsers, without an Envoy
+// proxy in front.
+//
+// The connect-go handlers generated from proto/buf.gen.yaml already speak
+// gRPC, gRPC-Web and the Connect protocol on the same routes. What a web
+// wallet additionally needs is CORS, so that the browser lets it call the
+// server from another origin, and a server that accepts both HTTP/1.1 (used
+// by browsers for gRPC-Web) and cleartext HTTP/2 (used by gRPC clients).
+// Handler and NewServer provide those.
+//
+// Services implemented against the grpc-go interfaces can't be served this
+// way; implement the connect-go handler interfaces instead.
+package grpcweb
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Headers that gRPC-Web and Connect clients send, and that the browser must
+// be told are allowed.
+var allowedHeaders = []string{
+	"Content-Type",
+	"Connect-Protocol-Version",
+	"Connect-Timeout-Ms",
+	"Grpc-Timeout",
+	"X-Grpc-Web",
+	"X-User-Agent",
+}
+
+// Headers that the browser must expose to the client, which reads the RPC
+// status from them.
+var exposedHeaders = []string{
+	"Grpc-Status",
+	"Grpc-Message",
+	"Grpc-Status-Details-Bin",
+}
+
+// CORSOptions configures the CORS policy applied by Handler.
+type CORSOptions struct {
+	// AllowedOrigins lists the origins, such as "https://wallet.example",
+	// that may call the server. "*" allows any origin. If empty, no
+	// cross-origin requests are allowed.
+	AllowedOrigins []string
+	// AllowedHeaders lists request headers to allow in addition to the ones
+	// used by gRPC-Web and Connect, such as "Authorization".
+	AllowedHeaders []string
+	// MaxAge is how long browsers may cache a preflight response. Zero
+	// leaves it to the browser's default.
+	MaxAge time.Duration
+}
+
+// Handler wraps h with the CORS policy in opts.
+func Handler(h http.Handler, opts CORSOptions) http.Handler {
+	allowHeaders := strings.Join(append(slices.Clone(allowedHeaders), opts.AllowedHeaders...), ", ")
+	exposeHeaders := strings.Join(exposedHeaders, ", ")
+	anyOrigin := slices.Contains(opts.AllowedOrigins, "*")
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			h.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Add("Vary", "Origin")
+		if !anyOrigin && !slices.Contains(opts.AllowedOrigins, origin) {
+			if isPreflight(r) {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			// Let the request through; the browser withholds the response
+			// from the page because no CORS headers are set.
+			h.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		if isPreflight(r) {
+			w.Header().Add("Vary", "Access-Control-Request-Method")
+			w.Header().Add("Vary", "Access-Control-Request-Headers")
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST")
+			w.Header().Set("Access-Control-Allow-Headers", allowHeaders)
+			if opts.MaxAge > 0 {
+				w.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(opts.MaxAge.Seconds())))
+			}
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.Header().Set("Access-Control-Expose-Headers", exposeHeaders)
+		h.ServeHTTP(w, r)
+	})
+}
+
+func isPreflight(r *http.Request) bool {
+	return r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+}
+
+// NewServer returns an http.Server for addr that serves h, wrapped with the
+// CORS policy in opts, over both HTTP/1.1 and cleartext HTTP/2. Use
+// ListenAndServeTLS to serve over TLS instead.
+func NewServer(addr string, h http.Handler, opts CORSOptions) *http.Server {
+	var protocols http.Protocols
+	protocols.SetHTTP1(true)
+	protocols.SetHTTP2(true)
+	protocols.SetUnencryptedHTTP2(true)
+	return &http.Server{
+		Addr:              addr,
+		Handler:           Handler(h, opts),
+		Protocols:         &protocols,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+}
@@ -0,0 +1,131 @@
+package grpcweb
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+var ok = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Grpc-Status", "0")
+	io.WriteString(w, r.Proto)
+})
+
+func TestHandlerPreflight(t *testing.T) {
+	h := Handler(ok, CORSOptions{
+		AllowedOrigins: []string{"https://wallet.example"},
+		AllowedHeaders: []string{"Authorization"},
+		MaxAge:         time.Hour,
+	})
+
+	req := httptest.NewRequest(http.MethodOptions, "/penumbra.view.v1.ViewService/Status", nil)
+	req.Header.Set("Origin", "https://wallet.example")
+	req.Header.Set("Access-Control-Request-Method", "POST")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("preflight status = %d, want %d", rec.Code, http.StatusNoContent)
+	}
+	for header, want := range map[string]string{
+		"Access-Control-Allow-Origin":  "https://wallet.example",
+		"Access-Control-Allow-Methods": "GET, POST",
+		"Access-Control-Max-Age":       "3600",
+	} {
+		if got := rec.Header().Get(header); got != want {
+			t.Errorf("%s = %q, want %q", header, got, want)
+		}
+	}
+	allow := rec.Header().Get("Access-Control-Allow-Headers")
+	for _, want := range []string{"X-Grpc-Web", "Connect-Protocol-Version", "Authorization"} {
+		if !strings.Contains(allow, want) {
+			t.Errorf("Access-Control-Allow-Headers = %q, missing %s", allow, want)
+		}
+	}
+}
+
+func TestHandlerOrigins(t *testing.T) {
+	h := Handler(ok, CORSOptions{AllowedOrigins: []string{"https://wallet.example"}})
+
+	for _, tc := range []struct {
+		origin    string
+		preflight bool
+		wantCode  int
+		wantAllow string
+	}{
+		{origin: "https://wallet.example", wantCode: http.StatusOK, wantAllow: "https://wallet.example"},
+		{origin: "https://evil.example", wantCode: http.StatusOK},
+		{origin: "https://evil.example", preflight: true, wantCode: http.StatusForbidden},
+		{origin: "", wantCode: http.StatusOK},
+	} {
+		method := http.MethodPost
+		if tc.preflight {
+			method = http.MethodOptions
+		}
+		req := httptest.NewRequest(method, "/", nil)
+		if tc.origin != "" {
+			req.Header.Set("Origin", tc.origin)
+		}
+		if tc.preflight {
+			req.Header.Set("Access-Control-Request-Method", "POST")
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != tc.wantCode {
+			t.Errorf("origin %q preflight %v: status = %d, want %d", tc.origin, tc.preflight, rec.Code, tc.wantCode)
+		}
+		if got := rec.Header().Get("Access-Control-Allow-Origin"); got != tc.wantAllow {
+			t.Errorf("origin %q: Access-Control-Allow-Origin = %q, want %q", tc.origin, got, tc.wantAllow)
+		}
+		if tc.wantAllow != "" && !strings.Contains(rec.Header().Get("Access-Control-Expose-Headers"), "Grpc-Status") {
+			t.Errorf("origin %q: Grpc-Status not exposed", tc.origin)
+		}
+	}
+}
+
+func TestHandlerAnyOrigin(t *testing.T) {
+	h := Handler(ok, CORSOptions{AllowedOrigins: []string{"*"}})
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+	req.Header.Set("Origin", "http://localhost:5173")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "http://localhost:5173" {
+		t.Fatalf("Access-Control-Allow-Origin = %q, want the request origin", got)
+	}
+}
+
+func TestNewServerServesHTTP1AndH2C(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := NewServer(lis.Addr().String(), ok, CORSOptions{})
+	go srv.Serve(lis)
+	defer srv.Close()
+
+	var h2c http.Protocols
+	h2c.SetUnencryptedHTTP2(true)
+	for _, tc := range []struct {
+		name      string
+		transport *http.Transport
+		want      string
+	}{
+		{"HTTP/1.1", &http.Transport{}, "HTTP/1.1"},
+		{"h2c", &http.Transport{Protocols: &h2c}, "HTTP/2.0"},
+	} {
+		client := &http.Client{Transport: tc.transport}
+		resp, err := client.Post("http://"+lis.Addr().String()+"/", "application/grpc-web+proto", nil)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if string(body) != tc.want {
+			t.Errorf("%s: served over %q, want %q", tc.name, body, tc.want)
+		}
+	}
+}
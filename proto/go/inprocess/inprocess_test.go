@@ -0,0 +1,102 @@
+package inprocess
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func newHealth(t *testing.T, serverOpts ...grpc.ServerOption) (*health.Server, healthpb.HealthClient) {
+	t.Helper()
+	srv := health.NewServer()
+	conn, err := New(func(s grpc.ServiceRegistrar) {
+		healthpb.RegisterHealthServer(s, srv)
+	}, serverOpts)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return srv, healthpb.NewHealthClient(conn)
+}
+
+func TestUnary(t *testing.T) {
+	srv, client := newHealth(t)
+	srv.SetServingStatus("penumbra.view.v1.ViewService", healthpb.HealthCheckResponse_SERVING)
+
+	resp, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: "penumbra.view.v1.ViewService"})
+	if err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	if resp.Status != healthpb.HealthCheckResponse_SERVING {
+		t.Fatalf("Check = %v, want SERVING", resp.Status)
+	}
+
+	_, err = client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: "unknown"})
+	if status.Code(err) != codes.NotFound {
+		t.Fatalf("Check(unknown) = %v, want NotFound", err)
+	}
+}
+
+func TestStream(t *testing.T) {
+	srv, client := newHealth(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stream, err := client.Watch(ctx, &healthpb.HealthCheckRequest{Service: "custody"})
+	if err != nil {
+		t.Fatalf("Watch: %v", err)
+	}
+	resp, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("Recv: %v", err)
+	}
+	if resp.Status != healthpb.HealthCheckResponse_SERVICE_UNKNOWN {
+		t.Fatalf("first status = %v, want SERVICE_UNKNOWN", resp.Status)
+	}
+	srv.SetServingStatus("custody", healthpb.HealthCheckResponse_SERVING)
+	if resp, err = stream.Recv(); err != nil {
+		t.Fatalf("Recv: %v", err)
+	}
+	if resp.Status != healthpb.HealthCheckResponse_SERVING {
+		t.Fatalf("second status = %v, want SERVING", resp.Status)
+	}
+}
+
+func TestServerOptionsAndMetadata(t *testing.T) {
+	var got []string
+	intercept := grpc.UnaryInterceptor(func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		got = md.Get("authorization")
+		return handler(ctx, req)
+	})
+	_, client := newHealth(t, intercept)
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer token")
+	if _, err := client.Check(ctx, &healthpb.HealthCheckRequest{}); err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	if len(got) != 1 || got[0] != "Bearer token" {
+		t.Fatalf("server saw authorization %v, want [Bearer token]", got)
+	}
+}
+
+func TestClose(t *testing.T) {
+	srv := health.NewServer()
+	conn, err := New(func(s grpc.ServiceRegistrar) { healthpb.RegisterHealthServer(s, srv) }, nil)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if err := conn.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	_, err = healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{})
+	if status.Code(err) != codes.Canceled {
+		t.Fatalf("Check after Close = %v, want Canceled", err)
+	}
+}
@@ -0,0 +1,271 @@
+// Package jsonrpc serves Penumbra gRPC services as JSON-RPC 2.0 over HTTP,
+// for systems that can only make JSON-RPC calls.
+//
+//	h, err := jsonrpc.NewHandler(conn, "penumbra.view.v1.ViewService")
+//	http.ListenAndServe("127.0.0.1:8081", h)
+//
+// Each RPC method becomes a JSON-RPC method named by its full protobuf name,
+// such as "penumbra.view.v1.ViewService.Status". Params are the request
+// message as a JSON object and the result is the response message, both in
+// the canonical protobuf JSON mapping. A server-streaming method returns
+// the array of all its responses once the stream ends; streams that do not
+// end, such as StatusStream, hold the request until the client gives up, and
+// should be called over gRPC instead. Client-streaming and bidirectional
+// methods are not served.
+//
+// A gRPC error becomes JSON-RPC error -32000, with the message of the gRPC
+// status and the google.rpc.Status itself as its data. Batches and
+// notifications are supported. The HTTP Authorization header is forwarded
+// as authorization metadata, so rpcauth.Token works through the bridge.
+//
+// As with gateway, the custody service performs no authentication of its
+// own: only serve it on a listener that untrusted clients cannot reach.
+package jsonrpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/penumbra-zone/penumbra/proto/go/descriptors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// Error codes defined by the JSON-RPC 2.0 specification, and the one used
+// for errors returned by the gRPC server.
+const (
+	CodeParseError     = -32700
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeInternalError  = -32603
+	CodeServerError    = -32000
+)
+
+// maxRequestBytes bounds the body of a request, which for a batch holds
+// every call in it.
+const maxRequestBytes = 16 << 20
+
+type handler struct {
+	conn    grpc.ClientConnInterface
+	methods map[string]protoreflect.MethodDescriptor
+}
+
+// NewHandler returns an http.Handler that serves the unary and
+// server-streaming methods of the named services, calling them on conn.
+// The services are looked up in the descriptors package, so conn may be
+// any gRPC server for them, whether or not its Go types are linked in.
+func NewHandler(conn grpc.ClientConnInterface, services ...string) (http.Handler, error) {
+	if len(services) == 0 {
+		return nil, fmt.Errorf("jsonrpc: no services to serve")
+	}
+	h := &handler{conn: conn, methods: map[string]protoreflect.MethodDescriptor{}}
+	for _, name := range services {
+		d, err := descriptors.Files().FindDescriptorByName(protoreflect.FullName(name))
+		if err != nil {
+			return nil, fmt.Errorf("jsonrpc: %s: %w", name, err)
+		}
+		sd, ok := d.(protoreflect.ServiceDescriptor)
+		if !ok {
+			return nil, fmt.Errorf("jsonrpc: %s is not a service", name)
+		}
+		for i := 0; i < sd.Methods().Len(); i++ {
+			md := sd.Methods().Get(i)
+			if !md.IsStreamingClient() {
+				h.methods[string(md.FullName())] = md
+			}
+		}
+	}
+	return h, nil
+}
+
+type request struct {
+	Version string          `json:"jsonrpc"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	// ID is nil for a notification, and "null" for a request with a null
+	// ID, which still gets a response.
+	ID json.RawMessage `json:"id,omitempty"`
+}
+
+type response struct {
+	Version string          `json:"jsonrpc"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+	ID      json.RawMessage `json:"id"`
+}
+
+// rpcError is a JSON-RPC error object.
+type rpcError struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "JSON-RPC requests must be POSTed", http.StatusMethodNotAllowed)
+		return
+	}
+	ctx := r.Context()
+	if auth := r.Header.Get("Authorization"); auth != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, "authorization", auth)
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestBytes))
+	if err != nil {
+		write(w, errorResponse(CodeParseError, err.Error()))
+		return
+	}
+	body = bytes.TrimSpace(body)
+	if len(body) == 0 || body[0] != '[' {
+		if resp, ok := h.call(ctx, body); ok {
+			write(w, resp)
+		} else {
+			w.WriteHeader(http.StatusNoContent)
+		}
+		return
+	}
+
+	var batch []json.RawMessage
+	if err := json.Unmarshal(body, &batch); err != nil {
+		write(w, errorResponse(CodeParseError, err.Error()))
+		return
+	}
+	if len(batch) == 0 {
+		write(w, errorResponse(CodeInvalidRequest, "empty batch"))
+		return
+	}
+	var resps []response
+	for _, b := range batch {
+		if resp, ok := h.call(ctx, b); ok {
+			resps = append(resps, resp)
+		}
+	}
+	if len(resps) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	write(w, resps)
+}
+
+// call handles one request, and reports whether it needs a response.
+func (h *handler) call(ctx context.Context, b []byte) (response, bool) {
+	var req request
+	if err := json.Unmarshal(b, &req); err != nil {
+		var syntax *json.SyntaxError
+		if errors.As(err, &syntax) {
+			return errorResponse(CodeParseError, err.Error()), true
+		}
+		return errorResponse(CodeInvalidRequest, err.Error()), true
+	}
+	resp := response{Version: "2.0", ID: req.ID}
+	if req.Version != "2.0" || req.Method == "" {
+		resp.Error = &rpcError{Code: CodeInvalidRequest, Message: `requests must have "jsonrpc": "2.0" and a method`}
+		return resp, true
+	}
+	resp.Result, resp.Error = h.invoke(ctx, req)
+	return resp, req.ID != nil
+}
+
+func (h *handler) invoke(ctx context.Context, req request) (json.RawMessage, *rpcError) {
+	md, ok := h.methods[req.Method]
+	if !ok {
+		return nil, &rpcError{Code: CodeMethodNotFound, Message: "method not found: " + req.Method}
+	}
+	in := dynamicpb.NewMessage(md.Input())
+	if params := bytes.TrimSpace(req.Params); len(params) > 0 && !bytes.Equal(params, []byte("null")) {
+		if params[0] != '{' {
+			return nil, &rpcError{Code: CodeInvalidParams, Message: "params must be an object"}
+		}
+		if err := (protojson.UnmarshalOptions{Resolver: descriptors.Types()}).Unmarshal(params, in); err != nil {
+			return nil, &rpcError{Code: CodeInvalidParams, Message: err.Error()}
+		}
+	}
+
+	method := "/" + string(md.Parent().FullName()) + "/" + string(md.Name())
+	if !md.IsStreamingServer() {
+		out := dynamicpb.NewMessage(md.Output())
+		if err := h.conn.Invoke(ctx, method, in, out); err != nil {
+			return nil, grpcError(err)
+		}
+		return marshal(out)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stream, err := h.conn.NewStream(ctx, &grpc.StreamDesc{ServerStreams: true}, method)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	if err := stream.SendMsg(in); err != nil {
+		return nil, grpcError(err)
+	}
+	if err := stream.CloseSend(); err != nil {
+		return nil, grpcError(err)
+	}
+	results := []json.RawMessage{}
+	for {
+		out := dynamicpb.NewMessage(md.Output())
+		if err := stream.RecvMsg(out); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, grpcError(err)
+		}
+		b, rerr := marshal(out)
+		if rerr != nil {
+			return nil, rerr
+		}
+		results = append(results, b)
+	}
+	b, err := json.Marshal(results)
+	if err != nil {
+		return nil, &rpcError{Code: CodeInternalError, Message: err.Error()}
+	}
+	return b, nil
+}
+
+func marshal(m proto.Message) (json.RawMessage, *rpcError) {
+	b, err := (protojson.MarshalOptions{Resolver: descriptors.Types()}).Marshal(m)
+	if err != nil {
+		return nil, &rpcError{Code: CodeInternalError, Message: err.Error()}
+	}
+	return b, nil
+}
+
+// grpcError converts an error from the gRPC server, attaching its status.
+// Details whose types are not linked into the binary are dropped.
+func grpcError(err error) *rpcError {
+	st := status.Convert(err)
+	e := &rpcError{Code: CodeServerError, Message: st.Message()}
+	p := st.Proto()
+	data, merr := protojson.Marshal(p)
+	if merr != nil {
+		p.Details = nil
+		data, merr = protojson.Marshal(p)
+	}
+	if merr == nil {
+		e.Data = data
+	}
+	return e
+}
+
+func write(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+// errorResponse is a response to a request whose ID could not be read.
+func errorResponse(code int, message string) response {
+	return response{Version: "2.0", Error: &rpcError{Code: code, Message: message}, ID: json.RawMessage("null")}
+}
@@ -0,0 +1,210 @@
+package jsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/penumbra-zone/penumbra/proto/go/descriptors"
+	"github.com/penumbra-zone/penumbra/proto/go/inprocess"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// newMessage returns a dynamic message given in protojson. It panics on
+// error, as it is called from server goroutines with constant arguments.
+func newMessage(name, s string) *dynamicpb.Message {
+	md, err := descriptors.MessageDescriptor(name)
+	if err != nil {
+		panic(err)
+	}
+	m := dynamicpb.NewMessage(md)
+	if err := protojson.Unmarshal([]byte(s), m); err != nil {
+		panic(err)
+	}
+	return m
+}
+
+// viewServer serves Status, which reports the authorization it was called
+// with as its height, AddressByIndex, which fails, and Balances, which
+// streams two responses.
+func viewServer(t *testing.T) grpc.ClientConnInterface {
+	unary := func(name string, handle func(ctx context.Context, req protoreflect.Message) (any, error)) grpc.MethodDesc {
+		return grpc.MethodDesc{
+			MethodName: name,
+			Handler: func(_ any, ctx context.Context, dec func(any) error, _ grpc.UnaryServerInterceptor) (any, error) {
+				req := newMessage("penumbra.view.v1."+name+"Request", "{}")
+				if err := dec(req); err != nil {
+					return nil, err
+				}
+				return handle(ctx, req)
+			},
+		}
+	}
+	desc := grpc.ServiceDesc{
+		ServiceName: "penumbra.view.v1.ViewService",
+		HandlerType: (*any)(nil),
+		Methods: []grpc.MethodDesc{
+			unary("Status", func(ctx context.Context, _ protoreflect.Message) (any, error) {
+				md, _ := metadata.FromIncomingContext(ctx)
+				height := "5"
+				if auth := md.Get("authorization"); len(auth) == 1 && auth[0] == "Bearer secret" {
+					height = "10"
+				}
+				return newMessage("penumbra.view.v1.StatusResponse", `{"fullSyncHeight": "`+height+`"}`), nil
+			}),
+			unary("AddressByIndex", func(context.Context, protoreflect.Message) (any, error) {
+				return nil, status.Error(codes.NotFound, "no such account")
+			}),
+		},
+		Streams: []grpc.StreamDesc{{
+			StreamName:    "Balances",
+			ServerStreams: true,
+			Handler: func(_ any, stream grpc.ServerStream) error {
+				if err := stream.RecvMsg(newMessage("penumbra.view.v1.BalancesRequest", "{}")); err != nil {
+					return err
+				}
+				for _, account := range []string{"1", "2"} {
+					resp := newMessage("penumbra.view.v1.BalancesResponse", `{"account": {"account": `+account+`}}`)
+					if err := stream.SendMsg(resp); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		}},
+	}
+	conn, err := inprocess.New(func(s grpc.ServiceRegistrar) { s.RegisterService(&desc, nil) }, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestHandler(t *testing.T) {
+	h, err := NewHandler(viewServer(t), "penumbra.view.v1.ViewService")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	for _, tc := range []struct {
+		name, auth, body string
+		want             string // empty for no response
+	}{{
+		name: "unary",
+		body: `{"jsonrpc": "2.0", "id": 1, "method": "penumbra.view.v1.ViewService.Status"}`,
+		want: `{"jsonrpc": "2.0", "id": 1, "result": {"fullSyncHeight": "5"}}`,
+	}, {
+		name: "authorization",
+		auth: "Bearer secret",
+		body: `{"jsonrpc": "2.0", "id": "a", "method": "penumbra.view.v1.ViewService.Status", "params": {}}`,
+		want: `{"jsonrpc": "2.0", "id": "a", "result": {"fullSyncHeight": "10"}}`,
+	}, {
+		name: "stream",
+		body: `{"jsonrpc": "2.0", "id": 2, "method": "penumbra.view.v1.ViewService.Balances", "params": {"accountFilter": {"account": 1}}}`,
+		want: `{"jsonrpc": "2.0", "id": 2, "result": [{"account": {"account": 1}}, {"account": {"account": 2}}]}`,
+	}, {
+		name: "grpc error",
+		body: `{"jsonrpc": "2.0", "id": 3, "method": "penumbra.view.v1.ViewService.AddressByIndex"}`,
+		want: `{"jsonrpc": "2.0", "id": 3, "error": {"code": -32000, "message": "no such account", "data": {"code": 5, "message": "no such account"}}}`,
+	}, {
+		name: "unknown method",
+		body: `{"jsonrpc": "2.0", "id": 4, "method": "penumbra.view.v1.ViewService.Nope"}`,
+		want: `{"jsonrpc": "2.0", "id": 4, "error": {"code": -32601, "message": "method not found: penumbra.view.v1.ViewService.Nope"}}`,
+	}, {
+		name: "invalid params",
+		body: `{"jsonrpc": "2.0", "id": 5, "method": "penumbra.view.v1.ViewService.Status", "params": [1]}`,
+		want: `{"jsonrpc": "2.0", "id": 5, "error": {"code": -32602, "message": "params must be an object"}}`,
+	}, {
+		name: "parse error",
+		body: `{"jsonrpc": "2.0"`,
+		want: `{"jsonrpc": "2.0", "id": null, "error": {"code": -32700, "message": "unexpected end of JSON input"}}`,
+	}, {
+		name: "notification",
+		body: `{"jsonrpc": "2.0", "method": "penumbra.view.v1.ViewService.Status"}`,
+	}, {
+		name: "batch",
+		body: `[
+			{"jsonrpc": "2.0", "id": 1, "method": "penumbra.view.v1.ViewService.Status"},
+			{"jsonrpc": "2.0", "method": "penumbra.view.v1.ViewService.Status"},
+			{"jsonrpc": "1.0", "id": 2, "method": "penumbra.view.v1.ViewService.Status"}
+		]`,
+		want: `[
+			{"jsonrpc": "2.0", "id": 1, "result": {"fullSyncHeight": "5"}},
+			{"jsonrpc": "2.0", "id": 2, "error": {"code": -32600, "message": "requests must have \"jsonrpc\": \"2.0\" and a method"}}
+		]`,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPost, srv.URL, strings.NewReader(tc.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tc.auth != "" {
+				req.Header.Set("Authorization", tc.auth)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			body, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tc.want == "" {
+				if resp.StatusCode != http.StatusNoContent || len(body) != 0 {
+					t.Errorf("response = %d %s, want 204 and no body", resp.StatusCode, body)
+				}
+				return
+			}
+			var got, want any
+			if err := json.Unmarshal(body, &got); err != nil {
+				t.Fatalf("response %s: %v", body, err)
+			}
+			if err := json.Unmarshal([]byte(tc.want), &want); err != nil {
+				t.Fatal(err)
+			}
+			g, _ := json.Marshal(got)
+			w, _ := json.Marshal(want)
+			if string(g) != string(w) {
+				t.Errorf("response =\n%s\nwant\n%s", g, w)
+			}
+		})
+	}
+}
+
+func TestNewHandlerErrors(t *testing.T) {
+	for _, services := range [][]string{
+		nil,
+		{"penumbra.view.v1.NoSuchService"},
+		{"penumbra.view.v1.StatusRequest"},
+	} {
+		if _, err := NewHandler(nil, services...); err == nil {
+			t.Errorf("NewHandler(%q) succeeded", services)
+		}
+	}
+}
+
+func TestGetNotAllowed(t *testing.T) {
+	h, err := NewHandler(nil, "penumbra.view.v1.ViewService")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("GET = %d, want 405", rec.Code)
+	}
+}
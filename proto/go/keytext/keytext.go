@@ -0,0 +1,180 @@
+// Package keytext gives the raw bytes of Penumbra keys and addresses the
+// text forms the Rust tooling uses, so that logging, encoding/json, config
+// files and flags show "penumbra1…" instead of a byte dump.
+//
+// Each type is a named byte slice, converted from the bytes field of the
+// generated message:
+//
+//	slog.Info("sending", "to", keytext.Address(addr.GetInner()))
+//
+// Every type implements fmt.Stringer, encoding.TextMarshaler and
+// encoding.TextUnmarshaler. UnmarshalText accepts the Bech32m form and
+// checks the decoded length.
+//
+// SpendKey is a secret: its String, GoString, Format, MarshalText and LogValue
+// return a redacted placeholder, so a key cannot reach a log or an API
+// response by accident. Bech32m returns the real encoding when it is
+// wanted.
+package keytext
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+
+	"github.com/penumbra-zone/penumbra/proto/go/bech32m"
+)
+
+// Redacted replaces the text of a SpendKey.
+const Redacted = "penumbraspendkey1[REDACTED]"
+
+// Address is the 80-byte inner encoding of a penumbra.core.keys.v1.Address.
+type Address []byte
+
+// AssetID is the 32-byte inner encoding of a penumbra.core.asset.v1.AssetId.
+type AssetID []byte
+
+// FullViewingKey is the 64-byte inner encoding of a
+// penumbra.core.keys.v1.FullViewingKey.
+type FullViewingKey []byte
+
+// WalletID is the 32-byte inner encoding of a penumbra.core.keys.v1.WalletId.
+type WalletID []byte
+
+// IdentityKey is the 32-byte ik of a penumbra.core.keys.v1.IdentityKey.
+type IdentityKey []byte
+
+// GovernanceKey is the 32-byte gk of a penumbra.core.keys.v1.GovernanceKey.
+type GovernanceKey []byte
+
+// PositionID is the 32-byte inner encoding of a
+// penumbra.core.component.dex.v1.PositionId.
+type PositionID []byte
+
+// AuctionID is the 32-byte inner encoding of a
+// penumbra.core.component.auction.v1.AuctionId.
+type AuctionID []byte
+
+// SpendKey is the 32-byte inner encoding of a penumbra.core.keys.v1.SpendKey.
+type SpendKey []byte
+
+func (a Address) String() string        { return encode(bech32m.AddressPrefix, a) }
+func (a AssetID) String() string        { return encode(bech32m.AssetIDPrefix, a) }
+func (k FullViewingKey) String() string { return encode(bech32m.FullViewingKeyPrefix, k) }
+func (w WalletID) String() string       { return encode(bech32m.WalletIDPrefix, w) }
+func (k IdentityKey) String() string    { return encode(bech32m.ValidatorIdentityKeyPrefix, k) }
+func (k GovernanceKey) String() string  { return encode(bech32m.ValidatorGovernanceKeyPrefix, k) }
+func (p PositionID) String() string     { return encode(bech32m.PositionIDPrefix, p) }
+func (a AuctionID) String() string      { return encode(bech32m.AuctionIDPrefix, a) }
+
+func (a Address) MarshalText() ([]byte, error)        { return []byte(a.String()), nil }
+func (a AssetID) MarshalText() ([]byte, error)        { return []byte(a.String()), nil }
+func (k FullViewingKey) MarshalText() ([]byte, error) { return []byte(k.String()), nil }
+func (w WalletID) MarshalText() ([]byte, error)       { return []byte(w.String()), nil }
+func (k IdentityKey) MarshalText() ([]byte, error)    { return []byte(k.String()), nil }
+func (k GovernanceKey) MarshalText() ([]byte, error)  { return []byte(k.String()), nil }
+func (p PositionID) MarshalText() ([]byte, error)     { return []byte(p.String()), nil }
+func (a AuctionID) MarshalText() ([]byte, error)      { return []byte(a.String()), nil }
+
+// UnmarshalText accepts both Bech32m addresses and Bech32 compat addresses.
+func (a *Address) UnmarshalText(text []byte) error {
+	if strings.HasPrefix(string(text), bech32m.CompatAddressPrefix) {
+		b, err := bech32m.DecodeBech32(bech32m.CompatAddressPrefix, string(text))
+		if err != nil {
+			return err
+		}
+		return set((*[]byte)(a), b, 80)
+	}
+	return decode((*[]byte)(a), bech32m.AddressPrefix, text, 80)
+}
+
+func (a *AssetID) UnmarshalText(text []byte) error {
+	return decode((*[]byte)(a), bech32m.AssetIDPrefix, text, 32)
+}
+
+func (k *FullViewingKey) UnmarshalText(text []byte) error {
+	return decode((*[]byte)(k), bech32m.FullViewingKeyPrefix, text, 64)
+}
+
+func (w *WalletID) UnmarshalText(text []byte) error {
+	return decode((*[]byte)(w), bech32m.WalletIDPrefix, text, 32)
+}
+
+func (k *IdentityKey) UnmarshalText(text []byte) error {
+	return decode((*[]byte)(k), bech32m.ValidatorIdentityKeyPrefix, text, 32)
+}
+
+func (k *GovernanceKey) UnmarshalText(text []byte) error {
+	return decode((*[]byte)(k), bech32m.ValidatorGovernanceKeyPrefix, text, 32)
+}
+
+func (p *PositionID) UnmarshalText(text []byte) error {
+	return decode((*[]byte)(p), bech32m.PositionIDPrefix, text, 32)
+}
+
+func (a *AuctionID) UnmarshalText(text []byte) error {
+	return decode((*[]byte)(a), bech32m.AuctionIDPrefix, text, 32)
+}
+
+// Bech32m returns the encoding of the key, for the rare places it must be
+// shown or stored, such as a key export.
+func (k SpendKey) Bech32m() string { return encode(bech32m.SpendKeyPrefix, k) }
+
+func (k SpendKey) String() string { return Redacted }
+
+// GoString keeps %#v from printing the bytes.
+func (k SpendKey) GoString() string { return "keytext.SpendKey(" + Redacted + ")" }
+
+// Format redacts the key for every verb, including those such as %d that
+// would otherwise print the bytes.
+func (k SpendKey) Format(f fmt.State, verb rune) {
+	if verb == 'v' && f.Flag('#') {
+		io.WriteString(f, k.GoString())
+		return
+	}
+	io.WriteString(f, Redacted)
+}
+
+// MarshalText returns Redacted, so the key cannot be encoded by accident;
+// write Bech32m to store it.
+func (k SpendKey) MarshalText() ([]byte, error) { return []byte(Redacted), nil }
+
+// LogValue redacts the key in log/slog output.
+func (k SpendKey) LogValue() slog.Value { return slog.StringValue(Redacted) }
+
+// UnmarshalText reads the Bech32m encoding, so a key can be loaded from a
+// config file.
+func (k *SpendKey) UnmarshalText(text []byte) error {
+	return decode((*[]byte)(k), bech32m.SpendKeyPrefix, text, 32)
+}
+
+// encode returns the Bech32m encoding of b, or "" if b is empty.
+func encode(prefix string, b []byte) string {
+	if len(b) == 0 {
+		return ""
+	}
+	s, err := bech32m.Encode(prefix, b)
+	if err != nil {
+		// The prefixes are constants that Encode accepts.
+		panic(err)
+	}
+	return s
+}
+
+// decode sets *dst to the n bytes encoded in text.
+func decode(dst *[]byte, prefix string, text []byte, n int) error {
+	b, err := bech32m.Decode(prefix, string(text))
+	if err != nil {
+		return err
+	}
+	return set(dst, b, n)
+}
+
+func set(dst *[]byte, b []byte, n int) error {
+	if len(b) != n {
+		return fmt.Errorf("keytext: decoded %d bytes, expected %d", len(b), n)
+	}
+	*dst = b
+	return nil
+}
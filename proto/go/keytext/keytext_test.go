@@ -0,0 +1,81 @@
+package keytext
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+const validAddress = "penumbra147mfall0zr6am5r45qkwht7xqqrdsp50czde7empv7yq2nk3z8yyfh9k9520ddgswkmzar22vhz9dwtuem7uxw0qytfpv7lk3q9dp8ccaw2fn5c838rfackazmgf3ahh09cxmz"
+
+func TestAddressRoundTrip(t *testing.T) {
+	var a Address
+	if err := a.UnmarshalText([]byte(validAddress)); err != nil {
+		t.Fatal(err)
+	}
+	if len(a) != 80 {
+		t.Fatalf("decoded %d bytes, want 80", len(a))
+	}
+	if got := a.String(); got != validAddress {
+		t.Errorf("String = %s, want %s", got, validAddress)
+	}
+	b, err := json.Marshal(map[string]Address{"to": a})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"to":"` + validAddress + `"}`; string(b) != want {
+		t.Errorf("json.Marshal = %s, want %s", b, want)
+	}
+}
+
+func TestUnmarshalTextErrors(t *testing.T) {
+	var a Address
+	if err := a.UnmarshalText([]byte(validAddress[:len(validAddress)-1] + "q")); err == nil {
+		t.Error("Address.UnmarshalText accepted a bad checksum")
+	}
+	var id AssetID
+	if err := id.UnmarshalText([]byte(validAddress)); err == nil {
+		t.Error("AssetID.UnmarshalText accepted an address")
+	}
+	short := Address(make([]byte, 32)).String()
+	if err := a.UnmarshalText([]byte(short)); err == nil || !strings.Contains(err.Error(), "expected 80") {
+		t.Errorf("Address.UnmarshalText(32 bytes) = %v, want a length error", err)
+	}
+}
+
+func TestSpendKeyRedacted(t *testing.T) {
+	sk := SpendKey(bytes.Repeat([]byte{7}, 32))
+	encoded := sk.Bech32m()
+	if !strings.HasPrefix(encoded, "penumbraspendkey1") {
+		t.Fatalf("Bech32m = %s", encoded)
+	}
+
+	var log bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&log, nil))
+	logger.Info("key", "sk", sk)
+	b, err := json.Marshal(struct{ SK SpendKey }{sk})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, out := range []string{
+		fmt.Sprint(sk), fmt.Sprintf("%v %+v %#v %s %q %d %x", sk, sk, sk, sk, sk, sk, sk), string(b), log.String(),
+	} {
+		if strings.Contains(out, encoded) || strings.Contains(out, "7 7 7") || strings.Contains(out, "0707") {
+			t.Errorf("key leaked: %s", out)
+		}
+		if !strings.Contains(out, Redacted) {
+			t.Errorf("%s does not contain %s", out, Redacted)
+		}
+	}
+
+	var got SpendKey
+	if err := got.UnmarshalText([]byte(encoded)); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, sk) {
+		t.Error("UnmarshalText did not restore the key")
+	}
+}
@@ -0,0 +1,318 @@
+// Package lazyblock reads compact blocks without decoding them, for wallets
+// syncing the full chain history.
+//
+// Almost every state payload in a compact block belongs to someone else, and
+// a wallet discovers that by trial-decrypting the payload's ciphertext. Fully
+// unmarshalling each CompactBlock allocates a message and three byte slices
+// for every payload before that check can run. Block instead reads the
+// payloads in place: the ephemeral key and ciphertext handed to the check
+// are slices of the received bytes. Only the payloads that pass are
+// unmarshalled:
+//
+//	err := lazyblock.Range(ctx, conn, req, func(b lazyblock.Block) error {
+//		return b.Payloads(func(p lazyblock.Payload) error {
+//			if p.Kind != lazyblock.Note || !trialDecrypt(ivk, p.EphemeralKey, p.Ciphertext) {
+//				return nil
+//			}
+//			sp := new(compact_blockv1.StatePayload)
+//			if err := proto.Unmarshal(p.Raw, sp); err != nil {
+//				return err
+//			}
+//			...
+//		})
+//	})
+//
+// Range also avoids copying the blocks it receives: each Block is the buffer
+// grpc-go read it into, taken from and returned to grpc-go's buffer pool, so
+// a sync of any length allocates no buffers of its own.
+//
+// The trial decryption is the caller's: it needs the wallet's keys and the
+// decaf377 primitives, which are not part of this module.
+package lazyblock
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/mem"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+)
+
+// rangeMethod is the full method name of CompactBlockRange.
+const rangeMethod = "/penumbra.core.component.compact_block.v1.QueryService/CompactBlockRange"
+
+// Field numbers of the messages read by this package.
+const (
+	blockHeight        = 1 // CompactBlock.height
+	blockStatePayloads = 2 // CompactBlock.state_payloads
+	payloadRolledUp    = 2 // StatePayload.rolled_up
+	payloadNote        = 3 // StatePayload.note
+	payloadSwap        = 4 // StatePayload.swap
+	rolledUpCommitment = 1 // StatePayload.RolledUp.commitment
+	noteNote           = 2 // StatePayload.Note.note
+	swapSwap           = 2 // StatePayload.Swap.swap
+	noteCommitment     = 1 // NotePayload.note_commitment
+	noteEphemeralKey   = 2 // NotePayload.ephemeral_key
+	noteCiphertext     = 3 // NotePayload.encrypted_note
+	swapCommitment     = 1 // SwapPayload.commitment
+	swapCiphertext     = 2 // SwapPayload.encrypted_swap
+	innerBytes         = 1 // StateCommitment.inner, NoteCiphertext.inner
+	responseBlock      = 1 // CompactBlockRangeResponse.compact_block
+)
+
+// Kind is the kind of a state payload.
+type Kind int
+
+const (
+	// Unknown is a payload with none of the known kinds set, such as one
+	// added to the protocol after this package was written.
+	Unknown Kind = iota
+	// RolledUp is a commitment whose note or swap is not included.
+	RolledUp
+	// Note is a new note, encrypted to its recipient.
+	Note
+	// Swap is a new swap, encrypted to its sender.
+	Swap
+)
+
+func (k Kind) String() string {
+	switch k {
+	case RolledUp:
+		return "rolled_up"
+	case Note:
+		return "note"
+	case Swap:
+		return "swap"
+	default:
+		return "unknown"
+	}
+}
+
+// Payload is one StatePayload of a block. Its slices alias the block's
+// bytes, and are valid for as long as those are.
+type Payload struct {
+	Kind Kind
+	// Commitment is the 32-byte state commitment of the payload.
+	Commitment []byte
+	// EphemeralKey is the ephemeral public key of a Note; it is nil for
+	// other kinds.
+	EphemeralKey []byte
+	// Ciphertext is the encrypted note of a Note or the encrypted swap of
+	// a Swap; it is nil for RolledUp.
+	Ciphertext []byte
+	// Raw is the encoding of the StatePayload, to unmarshal the payloads
+	// that pass the caller's check.
+	Raw []byte
+}
+
+// Block is the encoding of a penumbra.core.component.compact_block.v1.CompactBlock.
+type Block []byte
+
+// Height returns the height of the block.
+func (b Block) Height() (uint64, error) {
+	var height uint64
+	err := walk(b, func(num protowire.Number, typ protowire.Type, v []byte, x uint64) error {
+		if num == blockHeight && typ == protowire.VarintType {
+			height = x
+		}
+		return nil
+	})
+	return height, err
+}
+
+// Payloads calls fn with each state payload of the block, in order. It
+// stops at the first error fn returns, and returns it.
+func (b Block) Payloads(fn func(Payload) error) error {
+	return walk(b, func(num protowire.Number, typ protowire.Type, v []byte, _ uint64) error {
+		if num != blockStatePayloads || typ != protowire.BytesType {
+			return nil
+		}
+		p, err := parsePayload(v)
+		if err != nil {
+			return err
+		}
+		return fn(p)
+	})
+}
+
+func parsePayload(raw []byte) (Payload, error) {
+	p := Payload{Raw: raw}
+	err := walk(raw, func(num protowire.Number, typ protowire.Type, v []byte, _ uint64) error {
+		if typ != protowire.BytesType {
+			return nil
+		}
+		// A oneof takes the last member on the wire, so each one resets p.
+		var err error
+		switch num {
+		case payloadRolledUp:
+			p = Payload{Kind: RolledUp, Raw: raw}
+			p.Commitment, err = inner(v, rolledUpCommitment)
+		case payloadNote:
+			p = Payload{Kind: Note, Raw: raw}
+			var note []byte
+			if note, err = last(v, noteNote); err != nil {
+				return err
+			}
+			if p.Commitment, err = inner(note, noteCommitment); err != nil {
+				return err
+			}
+			if p.EphemeralKey, err = last(note, noteEphemeralKey); err != nil {
+				return err
+			}
+			p.Ciphertext, err = inner(note, noteCiphertext)
+		case payloadSwap:
+			p = Payload{Kind: Swap, Raw: raw}
+			var swap []byte
+			if swap, err = last(v, swapSwap); err != nil {
+				return err
+			}
+			if p.Commitment, err = inner(swap, swapCommitment); err != nil {
+				return err
+			}
+			p.Ciphertext, err = last(swap, swapCiphertext)
+		}
+		return err
+	})
+	return p, err
+}
+
+// inner returns the inner bytes of the message in field num of b, which is
+// a StateCommitment or a NoteCiphertext.
+func inner(b []byte, num protowire.Number) ([]byte, error) {
+	m, err := last(b, num)
+	if err != nil {
+		return nil, err
+	}
+	return last(m, innerBytes)
+}
+
+// last returns the value of the last occurrence of the length-delimited
+// field num in b, which is the value a decoder would keep. Occurrences of
+// a message field are merged by a decoder rather than replaced; compact
+// blocks are written by the node with each field once, so the difference
+// is not observable for them.
+func last(b []byte, num protowire.Number) ([]byte, error) {
+	var out []byte
+	err := walk(b, func(n protowire.Number, typ protowire.Type, v []byte, _ uint64) error {
+		if n == num && typ == protowire.BytesType {
+			out = v
+		}
+		return nil
+	})
+	return out, err
+}
+
+// walk calls fn with each field of the message encoded in b: v is set for
+// length-delimited fields and x for varints.
+func walk(b []byte, fn func(num protowire.Number, typ protowire.Type, v []byte, x uint64) error) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return fmt.Errorf("lazyblock: %w", protowire.ParseError(n))
+		}
+		b = b[n:]
+		var v []byte
+		var x uint64
+		switch typ {
+		case protowire.BytesType:
+			v, n = protowire.ConsumeBytes(b)
+		case protowire.VarintType:
+			x, n = protowire.ConsumeVarint(b)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, b)
+		}
+		if n < 0 {
+			return fmt.Errorf("lazyblock: %w", protowire.ParseError(n))
+		}
+		b = b[n:]
+		if err := fn(num, typ, v, x); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Range calls CompactBlockRange on cc with req, a CompactBlockRangeRequest,
+// and calls fn with each block as it arrives. The Block passed to fn is
+// grpc-go's receive buffer, returned to its pool once fn returns: fn must
+// copy anything it keeps, including the slices of payloads. Range returns
+// nil when the stream ends, and otherwise the first error from the stream
+// or fn.
+func Range(ctx context.Context, cc grpc.ClientConnInterface, req proto.Message, fn func(Block) error, opts ...grpc.CallOption) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	desc := &grpc.StreamDesc{StreamName: "CompactBlockRange", ServerStreams: true}
+	stream, err := cc.NewStream(ctx, desc, rangeMethod, append(opts, grpc.ForceCodecV2(codec{}))...)
+	if err != nil {
+		return err
+	}
+	if err := stream.SendMsg(req); err != nil {
+		return err
+	}
+	if err := stream.CloseSend(); err != nil {
+		return err
+	}
+	resp := new(response)
+	defer resp.free()
+	for {
+		if err := stream.RecvMsg(resp); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		block, err := last(resp.buf.ReadOnlyData(), responseBlock)
+		if err != nil {
+			return err
+		}
+		if err := fn(block); err != nil {
+			return err
+		}
+	}
+}
+
+// response holds an encoded CompactBlockRangeResponse, in a buffer from
+// grpc-go's pool.
+type response struct {
+	buf mem.Buffer
+}
+
+func (r *response) free() {
+	if r.buf != nil {
+		r.buf.Free()
+		r.buf = nil
+	}
+}
+
+// codec marshals requests with proto.Marshal, and keeps responses as they
+// were received. grpc-go frees the received buffers once Unmarshal returns,
+// so Unmarshal takes its own reference, which the response holds until the
+// next message replaces it. A message that arrived in several buffers is
+// first copied into one, also taken from the pool.
+type codec struct{}
+
+func (codec) Name() string { return "proto" }
+
+func (codec) Marshal(v any) (mem.BufferSlice, error) {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("lazyblock: cannot marshal %T: not a proto.Message", v)
+	}
+	b, err := proto.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	return mem.BufferSlice{mem.SliceBuffer(b)}, nil
+}
+
+func (codec) Unmarshal(data mem.BufferSlice, v any) error {
+	r, ok := v.(*response)
+	if !ok {
+		return fmt.Errorf("lazyblock: cannot unmarshal into %T", v)
+	}
+	r.free()
+	r.buf = data.MaterializeToBuffer(mem.DefaultBufferPool())
+	return nil
+}
@@ -0,0 +1,266 @@
+package lazyblock
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/penumbra-zone/penumbra/proto/go/descriptors"
+	"github.com/penumbra-zone/penumbra/proto/go/inprocess"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/mem"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+const (
+	blockName    = "penumbra.core.component.compact_block.v1.CompactBlock"
+	requestName  = "penumbra.core.component.compact_block.v1.CompactBlockRangeRequest"
+	responseName = "penumbra.core.component.compact_block.v1.CompactBlockRangeResponse"
+)
+
+// message returns a dynamic message given in protojson.
+func message(tb testing.TB, name, s string) *dynamicpb.Message {
+	tb.Helper()
+	md, err := descriptors.MessageDescriptor(name)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	m := dynamicpb.NewMessage(md)
+	if err := (protojson.UnmarshalOptions{Resolver: descriptors.Types()}).Unmarshal([]byte(s), m); err != nil {
+		tb.Fatalf("protojson.Unmarshal(%s): %v", s, err)
+	}
+	return m
+}
+
+// block returns a CompactBlock at height with n copies of each kind of
+// payload.
+func block(tb testing.TB, height, n int) *dynamicpb.Message {
+	var payloads []string
+	for i := 0; i < n; i++ {
+		payloads = append(payloads,
+			`{"rolledUp": {"commitment": {"inner": "AQE="}}}`,
+			`{"source": {"transaction": {"id": "BQU="}}, "note": {"note": {
+				"noteCommitment": {"inner": "AgI="},
+				"ephemeralKey": "AwM=",
+				"encryptedNote": {"inner": "BAQ="}
+			}}}`,
+			`{"swap": {"swap": {"commitment": {"inner": "BgY="}, "encryptedSwap": "Bwc="}}}`,
+		)
+	}
+	return message(tb, blockName, fmt.Sprintf(`{
+		"height": "%d",
+		"statePayloads": [%s],
+		"nullifiers": [{"inner": "CAg="}],
+		"epochIndex": "3"
+	}`, height, strings.Join(payloads, ",")))
+}
+
+func marshal(tb testing.TB, m proto.Message) []byte {
+	tb.Helper()
+	b, err := proto.Marshal(m)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return b
+}
+
+func TestPayloads(t *testing.T) {
+	m := block(t, 42, 1)
+	b := Block(marshal(t, m))
+
+	height, err := b.Height()
+	if err != nil || height != 42 {
+		t.Errorf("Height() = %d, %v; want 42", height, err)
+	}
+
+	want := []Payload{
+		{Kind: RolledUp, Commitment: []byte{1, 1}},
+		{Kind: Note, Commitment: []byte{2, 2}, EphemeralKey: []byte{3, 3}, Ciphertext: []byte{4, 4}},
+		{Kind: Swap, Commitment: []byte{6, 6}, Ciphertext: []byte{7, 7}},
+	}
+	list := m.Get(m.Descriptor().Fields().ByName("state_payloads")).List()
+	var i int
+	err = b.Payloads(func(p Payload) error {
+		w := want[i]
+		if p.Kind != w.Kind || !bytes.Equal(p.Commitment, w.Commitment) ||
+			!bytes.Equal(p.EphemeralKey, w.EphemeralKey) || !bytes.Equal(p.Ciphertext, w.Ciphertext) {
+			t.Errorf("payload %d = %+v, want %+v", i, p, w)
+		}
+		got := dynamicpb.NewMessage(list.Get(i).Message().Descriptor())
+		if err := proto.Unmarshal(p.Raw, got); err != nil {
+			t.Errorf("payload %d: Unmarshal(Raw): %v", i, err)
+		} else if !proto.Equal(got, list.Get(i).Message().Interface()) {
+			t.Errorf("payload %d: Raw decodes to %v, want %v", i, got, list.Get(i).Message())
+		}
+		i++
+		return nil
+	})
+	if err != nil || i != len(want) {
+		t.Errorf("Payloads: %v after %d payloads, want %d", err, i, len(want))
+	}
+
+	stop := errors.New("stop")
+	i = 0
+	if err := b.Payloads(func(Payload) error { i++; return stop }); err != stop || i != 1 {
+		t.Errorf("Payloads with an error from fn = %v after %d payloads", err, i)
+	}
+
+	if err := Block(b[:len(b)-1]).Payloads(func(Payload) error { return nil }); err == nil {
+		t.Error("Payloads of a truncated block: want error")
+	}
+}
+
+// rawCodec passes messages through as encoded bytes, so the test server
+// costs next to nothing in the benchmarks.
+type rawCodec struct{}
+
+func (rawCodec) Name() string { return "proto" }
+
+func (rawCodec) Marshal(v any) (mem.BufferSlice, error) {
+	return mem.BufferSlice{mem.SliceBuffer(v.([]byte))}, nil
+}
+
+func (rawCodec) Unmarshal(data mem.BufferSlice, v any) error {
+	*v.(*[]byte) = data.Materialize()
+	return nil
+}
+
+// serve serves CompactBlockRange with blocks of the given number of each
+// kind of payload.
+func serve(tb testing.TB, payloads int) *inprocess.Conn {
+	responses := map[uint64][]byte{}
+	for h := uint64(0); h < 100; h++ {
+		resp := message(tb, responseName, `{}`)
+		resp.Set(resp.Descriptor().Fields().ByName("compact_block"),
+			protoreflect.ValueOfMessage(block(tb, int(h), payloads)))
+		responses[h] = marshal(tb, resp)
+	}
+	desc := grpc.ServiceDesc{
+		ServiceName: "penumbra.core.component.compact_block.v1.QueryService",
+		HandlerType: (*any)(nil),
+		Streams: []grpc.StreamDesc{{
+			StreamName:    "CompactBlockRange",
+			ServerStreams: true,
+			Handler: func(_ any, stream grpc.ServerStream) error {
+				var b []byte
+				if err := stream.RecvMsg(&b); err != nil {
+					return err
+				}
+				req := message(tb, requestName, `{}`)
+				if err := proto.Unmarshal(b, req); err != nil {
+					return err
+				}
+				fields := req.Descriptor().Fields()
+				start := req.Get(fields.ByName("start_height")).Uint()
+				end := req.Get(fields.ByName("end_height")).Uint()
+				for h := start; h <= end; h++ {
+					if err := stream.SendMsg(responses[h]); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		}},
+	}
+	conn, err := inprocess.New(func(s grpc.ServiceRegistrar) { s.RegisterService(&desc, nil) },
+		[]grpc.ServerOption{grpc.ForceServerCodecV2(rawCodec{})})
+	if err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestRange(t *testing.T) {
+	conn := serve(t, 2)
+	req := message(t, requestName, `{"startHeight": "10", "endHeight": "12"}`)
+	var heights []uint64
+	var notes int
+	err := Range(context.Background(), conn, req, func(b Block) error {
+		h, err := b.Height()
+		heights = append(heights, h)
+		if err != nil {
+			return err
+		}
+		return b.Payloads(func(p Payload) error {
+			if p.Kind == Note {
+				notes++
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		t.Fatalf("Range: %v", err)
+	}
+	if fmt.Sprint(heights) != "[10 11 12]" || notes != 6 {
+		t.Errorf("Range saw heights %v and %d notes, want [10 11 12] and 6", heights, notes)
+	}
+
+	stop := errors.New("stop")
+	if err := Range(context.Background(), conn, req, func(Block) error { return stop }); err != stop {
+		t.Errorf("Range with an error from fn = %v, want %v", err, stop)
+	}
+}
+
+// The benchmarks compare scanning a block for notes with unmarshalling it.
+// Unmarshalling into generated types allocates less than into dynamicpb,
+// but still once per message and byte slice.
+
+func BenchmarkPayloads(b *testing.B) {
+	raw := Block(marshal(b, block(b, 1, 100)))
+	b.ReportAllocs()
+	for b.Loop() {
+		var notes int
+		if err := raw.Payloads(func(p Payload) error {
+			if p.Kind == Note && p.EphemeralKey[0] == 3 {
+				notes++
+			}
+			return nil
+		}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkUnmarshal(b *testing.B) {
+	raw := marshal(b, block(b, 1, 100))
+	md, err := descriptors.MessageDescriptor(blockName)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	for b.Loop() {
+		if err := proto.Unmarshal(raw, dynamicpb.NewMessage(md)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkRange reports the cost of receiving blocks through Range,
+// which holds on to grpc-go's pooled buffers rather than copying each
+// block.
+func BenchmarkRange(b *testing.B) {
+	conn := serve(b, 100)
+	req := message(b, requestName, `{"startHeight": "0", "endHeight": "99"}`)
+	b.ReportAllocs()
+	for b.Loop() {
+		var notes int
+		err := Range(context.Background(), conn, req, func(b Block) error {
+			return b.Payloads(func(p Payload) error {
+				if p.Kind == Note {
+					notes++
+				}
+				return nil
+			})
+		})
+		if err != nil || notes != 100*100 {
+			b.Fatalf("Range: %v after %d notes", err, notes)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+// Package localrpc serves and dials the custody and view services over Unix
+// domain sockets, for a custodian or view daemon that runs on the same host
+// as the app using it.
+//
+// Access is controlled by the permissions of the socket file rather than by
+// TLS: a socket created with mode 0o600 can only be used by its owner.
+// Connections use grpc's local credentials, which report the connection as
+// secure, so per-RPC credentials that require transport security still work.
+//
+//	lis, err := localrpc.Listen("/run/penumbra/custody.sock", 0o600)
+//	s := grpc.NewServer(localrpc.ServerOptions()...)
+//	go s.Serve(lis)
+//
+//	conn, err := localrpc.Dial("/run/penumbra/custody.sock")
+//
+// Windows supports Unix domain sockets since Windows 10 version 1803, so the
+// same helpers work there, but the mode is not enforced: access is governed
+// by the ACL of the directory holding the socket. Named pipes are not
+// supported.
+package localrpc
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/penumbra-zone/penumbra/proto/go/rpcretry"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/local"
+)
+
+// Listen creates a Unix domain socket at path with the given permissions and
+// listens on it. A stale socket left at path by a process that exited is
+// replaced; a socket that still accepts connections is not.
+//
+// The socket only appears at path once its mode is 0o600, and is then
+// changed to perm, so it is never more accessible than intended.
+func Listen(path string, perm fs.FileMode) (net.Listener, error) {
+	if err := removeStale(path); err != nil {
+		return nil, err
+	}
+	lis, err := listen(path)
+	if err != nil {
+		return nil, fmt.Errorf("localrpc: %w", err)
+	}
+	if err := os.Chmod(path, perm); err != nil {
+		lis.Close()
+		return nil, fmt.Errorf("localrpc: %w", err)
+	}
+	return lis, nil
+}
+
+// removeStale removes the socket at path if no process is listening on it.
+func removeStale(path string) error {
+	fi, err := os.Lstat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("localrpc: %w", err)
+	}
+	if fi.Mode().Type() != fs.ModeSocket {
+		return fmt.Errorf("localrpc: %s exists and is not a socket", path)
+	}
+	if c, err := net.DialTimeout("unix", path, time.Second); err == nil {
+		c.Close()
+		return fmt.Errorf("localrpc: %s is in use", path)
+	}
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("localrpc: %w", err)
+	}
+	return nil
+}
+
+// listen binds the socket in a private temporary directory next to path and
+// renames it into place, so that it is never reachable with the permissions
+// given to it by the umask.
+func listen(path string) (net.Listener, error) {
+	dir, err := os.MkdirTemp(filepath.Dir(path), ".localrpc-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	if err := os.Chmod(dir, 0o700); err != nil {
+		return nil, err
+	}
+	tmp := filepath.Join(dir, "sock")
+	lis, err := net.Listen("unix", tmp)
+	if err != nil {
+		return nil, err
+	}
+	// The listener removes the socket file when closed; point it at the
+	// final path.
+	ul := lis.(*net.UnixListener)
+	ul.SetUnlinkOnClose(false)
+	if err := os.Chmod(tmp, 0o600); err != nil {
+		ul.Close()
+		return nil, err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		ul.Close()
+		return nil, err
+	}
+	return &unlinkListener{UnixListener: ul, path: path}, nil
+}
+
+// unlinkListener removes the socket file at its final path when closed.
+type unlinkListener struct {
+	*net.UnixListener
+	path string
+}
+
+func (l *unlinkListener) Close() error {
+	err := l.UnixListener.Close()
+	if rerr := os.Remove(l.path); err == nil && !errors.Is(rerr, fs.ErrNotExist) {
+		err = rerr
+	}
+	return err
+}
+
+// ServerOptions returns the options for a grpc.Server that serves on a
+// listener from Listen.
+func ServerOptions() []grpc.ServerOption {
+	return []grpc.ServerOption{grpc.Creds(local.NewCredentials())}
+}
+
+// Target returns the grpc target for the socket at path.
+func Target(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", fmt.Errorf("localrpc: %w", err)
+	}
+	return "unix://" + filepath.ToSlash(abs), nil
+}
+
+// Dial returns a client connection to the socket at path, with the retry
+// policies of rpcretry. opts are applied after the local transport
+// credentials and the retry policies, so they can add interceptors or
+// per-RPC credentials, or replace the service config.
+func Dial(path string, opts ...grpc.DialOption) (*grpc.ClientConn, error) {
+	target, err := Target(path)
+	if err != nil {
+		return nil, err
+	}
+	opts = append(append([]grpc.DialOption{grpc.WithTransportCredentials(local.NewCredentials())}, rpcretry.DialOptions()...), opts...)
+	return grpc.NewClient(target, opts...)
+}
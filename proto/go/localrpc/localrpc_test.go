@@ -0,0 +1,78 @@
+package localrpc
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func TestListenAndDial(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "view.sock")
+	lis, err := Listen(path, 0o600)
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	if runtime.GOOS != "windows" {
+		fi, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := fi.Mode().Perm(); got != 0o600 {
+			t.Errorf("socket mode = %v, want 0600", got)
+		}
+	}
+
+	s := grpc.NewServer(ServerOptions()...)
+	healthpb.RegisterHealthServer(s, health.NewServer())
+	go s.Serve(lis)
+	defer s.Stop()
+
+	conn, err := Dial(path)
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	defer conn.Close()
+	if _, err := healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{}); err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+
+	if _, err := Listen(path, 0o600); err == nil {
+		t.Error("Listen on a socket in use: want error")
+	}
+	s.Stop()
+	if _, err := os.Lstat(path); !os.IsNotExist(err) {
+		t.Errorf("socket remains after Stop: %v", err)
+	}
+}
+
+func TestListenStale(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "custody.sock")
+	stale, err := net.Listen("unix", path)
+	if err != nil {
+		t.Skipf("unix sockets unsupported: %v", err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	lis, err := Listen(path, 0o660)
+	if err != nil {
+		t.Fatalf("Listen over a stale socket: %v", err)
+	}
+	lis.Close()
+
+	file := filepath.Join(dir, "file")
+	if err := os.WriteFile(file, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Listen(file, 0o600); err == nil {
+		t.Error("Listen over a regular file: want error")
+	}
+}
@@ -0,0 +1,435 @@
+// Package pjson marshals Penumbra messages to JSON in the form used by the
+// Rust tooling for display, rather than the canonical protobuf JSON mapping:
+//
+//   - penumbra.core.keys.v1.Address is a Bech32m string ("penumbra1…")
+//   - penumbra.core.asset.v1.AssetId is a Bech32m string ("passet1…")
+//   - penumbra.core.num.v1.Amount is a decimal string ("1000000")
+//
+// Everything else follows protojson. Unmarshal accepts both these forms and
+// the canonical ones, so it can read the output of either encoder.
+package pjson
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"strings"
+
+	"github.com/penumbra-zone/penumbra/proto/go/bech32m"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+const (
+	addressName = "penumbra.core.keys.v1.Address"
+	assetIDName = "penumbra.core.asset.v1.AssetId"
+	amountName  = "penumbra.core.num.v1.Amount"
+)
+
+// MarshalOptions configures Marshal. The embedded protojson options are
+// applied before the Penumbra-specific rewriting.
+type MarshalOptions struct {
+	protojson.MarshalOptions
+}
+
+// Marshal encodes m using the default MarshalOptions.
+func Marshal(m proto.Message) ([]byte, error) {
+	return MarshalOptions{}.Marshal(m)
+}
+
+// Marshal encodes m as JSON.
+func (o MarshalOptions) Marshal(m proto.Message) ([]byte, error) {
+	po := o.MarshalOptions
+	po.Multiline, po.Indent = false, ""
+	b, err := po.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	v, err := parse(b)
+	if err != nil {
+		return nil, err
+	}
+	w := walker{resolver: o.Resolver, encode: true}
+	if v, err = w.message(v, m.ProtoReflect().Descriptor()); err != nil {
+		return nil, err
+	}
+	indent := ""
+	if o.Multiline {
+		indent = o.Indent
+		if indent == "" {
+			indent = "  "
+		}
+	}
+	var buf bytes.Buffer
+	if err := write(&buf, v, indent, 0); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalOptions configures Unmarshal. The embedded protojson options are
+// applied after the Penumbra-specific forms are rewritten to canonical JSON.
+type UnmarshalOptions struct {
+	protojson.UnmarshalOptions
+}
+
+// Unmarshal decodes b into m using the default UnmarshalOptions.
+func Unmarshal(b []byte, m proto.Message) error {
+	return UnmarshalOptions{}.Unmarshal(b, m)
+}
+
+// Unmarshal decodes b into m.
+func (o UnmarshalOptions) Unmarshal(b []byte, m proto.Message) error {
+	v, err := parse(b)
+	if err != nil {
+		return err
+	}
+	w := walker{resolver: o.Resolver}
+	if v, err = w.message(v, m.ProtoReflect().Descriptor()); err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err := write(&buf, v, "", 0); err != nil {
+		return err
+	}
+	return o.UnmarshalOptions.Unmarshal(buf.Bytes(), m)
+}
+
+// resolver is the subset of protoregistry.Types used to expand Any values.
+type resolver interface {
+	FindMessageByURL(url string) (protoreflect.MessageType, error)
+}
+
+// walker rewrites a parsed JSON value in place, guided by the message
+// descriptor. With encode set it converts canonical JSON to the Penumbra
+// forms, and the reverse otherwise.
+type walker struct {
+	resolver resolver
+	encode   bool
+}
+
+func (w walker) message(v any, md protoreflect.MessageDescriptor) (any, error) {
+	switch md.FullName() {
+	case addressName:
+		return w.bech32(v, bech32m.AddressPrefix, md)
+	case assetIDName:
+		return w.bech32(v, bech32m.AssetIDPrefix, md)
+	case amountName:
+		return w.amount(v)
+	case "google.protobuf.Any":
+		return w.any(v)
+	}
+	if md.ParentFile().Package() == "google.protobuf" {
+		// Well-known types have their own JSON forms.
+		return v, nil
+	}
+	obj, ok := v.(*object)
+	if !ok {
+		return v, nil
+	}
+	fields := md.Fields()
+	for i, key := range obj.keys {
+		fd := fields.ByJSONName(key)
+		if fd == nil {
+			fd = fields.ByTextName(key)
+		}
+		if fd == nil {
+			continue
+		}
+		var err error
+		if obj.vals[i], err = w.field(obj.vals[i], fd); err != nil {
+			return nil, err
+		}
+	}
+	return obj, nil
+}
+
+func (w walker) field(v any, fd protoreflect.FieldDescriptor) (any, error) {
+	switch {
+	case fd.IsMap():
+		vd := fd.MapValue()
+		if vd.Message() == nil {
+			return v, nil
+		}
+		obj, ok := v.(*object)
+		if !ok {
+			return v, nil
+		}
+		for i := range obj.vals {
+			var err error
+			if obj.vals[i], err = w.message(obj.vals[i], vd.Message()); err != nil {
+				return nil, err
+			}
+		}
+		return obj, nil
+	case fd.Message() == nil:
+		return v, nil
+	case fd.IsList():
+		list, ok := v.([]any)
+		if !ok {
+			return v, nil
+		}
+		for i := range list {
+			var err error
+			if list[i], err = w.message(list[i], fd.Message()); err != nil {
+				return nil, err
+			}
+		}
+		return list, nil
+	default:
+		return w.message(v, fd.Message())
+	}
+}
+
+// any rewrites the fields of the message packed in an Any, if its type can
+// be resolved. Packed messages that have a special JSON form themselves,
+// such as a bare Address, are left as protojson emits them.
+func (w walker) any(v any) (any, error) {
+	obj, ok := v.(*object)
+	if !ok {
+		return v, nil
+	}
+	url, ok := obj.get("@type").(string)
+	if !ok {
+		return v, nil
+	}
+	r := w.resolver
+	if r == nil {
+		r = protoregistry.GlobalTypes
+	}
+	mt, err := r.FindMessageByURL(url)
+	if err != nil {
+		// protojson reports unresolvable types itself.
+		return v, nil
+	}
+	switch md := mt.Descriptor(); md.FullName() {
+	case addressName, assetIDName, amountName:
+		return v, nil
+	default:
+		return w.message(obj, md)
+	}
+}
+
+func (w walker) bech32(v any, prefix string, md protoreflect.MessageDescriptor) (any, error) {
+	if !w.encode {
+		s, ok := v.(string)
+		if !ok {
+			return v, nil
+		}
+		b, err := bech32m.Decode(prefix, s)
+		if err != nil {
+			return nil, fmt.Errorf("pjson: %s: %w", md.FullName(), err)
+		}
+		return &object{keys: []string{"inner"}, vals: []any{base64.StdEncoding.EncodeToString(b)}}, nil
+	}
+	obj, ok := v.(*object)
+	if !ok {
+		return v, nil
+	}
+	// Only rewrite messages that carry just the inner bytes; the alternative
+	// representations are left for the reader to interpret.
+	var inner string
+	for i, key := range obj.keys {
+		s, _ := obj.vals[i].(string)
+		switch {
+		case key == "inner":
+			inner = s
+		case s != "":
+			return v, nil
+		}
+	}
+	if inner == "" {
+		return v, nil
+	}
+	b, err := base64.StdEncoding.DecodeString(inner)
+	if err != nil {
+		return nil, fmt.Errorf("pjson: %s: %w", md.FullName(), err)
+	}
+	return bech32m.Encode(prefix, b)
+}
+
+var maxAmount = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 128), big.NewInt(1))
+
+func (w walker) amount(v any) (any, error) {
+	mask := new(big.Int).SetUint64(^uint64(0))
+	if !w.encode {
+		s, ok := v.(string)
+		if !ok {
+			return v, nil
+		}
+		n, ok := new(big.Int).SetString(s, 10)
+		if !ok || n.Sign() < 0 || n.Cmp(maxAmount) > 0 {
+			return nil, fmt.Errorf("pjson: %s: %q is not an unsigned 128-bit decimal", amountName, s)
+		}
+		lo := new(big.Int).And(n, mask)
+		hi := new(big.Int).Rsh(n, 64)
+		return &object{keys: []string{"lo", "hi"}, vals: []any{lo.String(), hi.String()}}, nil
+	}
+	obj, ok := v.(*object)
+	if !ok {
+		return v, nil
+	}
+	var lo, hi big.Int
+	for i, key := range obj.keys {
+		var dst *big.Int
+		switch key {
+		case "lo":
+			dst = &lo
+		case "hi":
+			dst = &hi
+		default:
+			return v, nil
+		}
+		s, _ := obj.vals[i].(string)
+		if _, ok := dst.SetString(s, 10); !ok {
+			return nil, fmt.Errorf("pjson: %s: invalid %s %q", amountName, key, s)
+		}
+	}
+	return new(big.Int).Or(new(big.Int).Lsh(&hi, 64), &lo).String(), nil
+}
+
+// object is a JSON object that preserves key order, so output fields stay in
+// the order protojson emits them.
+type object struct {
+	keys []string
+	vals []any
+}
+
+func (o *object) get(key string) any {
+	for i, k := range o.keys {
+		if k == key {
+			return o.vals[i]
+		}
+	}
+	return nil
+}
+
+func parse(b []byte) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	v, err := parseValue(dec)
+	if err != nil {
+		return nil, fmt.Errorf("pjson: %w", err)
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, errors.New("pjson: unexpected data after top-level value")
+	}
+	return v, nil
+}
+
+func parseValue(dec *json.Decoder) (any, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch tok {
+	case json.Delim('{'):
+		obj := &object{}
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			v, err := parseValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			obj.keys = append(obj.keys, key.(string))
+			obj.vals = append(obj.vals, v)
+		}
+		_, err := dec.Token()
+		return obj, err
+	case json.Delim('['):
+		list := []any{}
+		for dec.More() {
+			v, err := parseValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+		_, err := dec.Token()
+		return list, err
+	default:
+		return tok, nil
+	}
+}
+
+func write(buf *bytes.Buffer, v any, indent string, depth int) error {
+	newline := func(depth int) {
+		if indent != "" {
+			buf.WriteByte('\n')
+			buf.WriteString(strings.Repeat(indent, depth))
+		}
+	}
+	switch v := v.(type) {
+	case *object:
+		if len(v.keys) == 0 {
+			buf.WriteString("{}")
+			return nil
+		}
+		buf.WriteByte('{')
+		for i, key := range v.keys {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			newline(depth + 1)
+			writeString(buf, key)
+			buf.WriteByte(':')
+			if indent != "" {
+				buf.WriteByte(' ')
+			}
+			if err := write(buf, v.vals[i], indent, depth+1); err != nil {
+				return err
+			}
+		}
+		newline(depth)
+		buf.WriteByte('}')
+	case []any:
+		if len(v) == 0 {
+			buf.WriteString("[]")
+			return nil
+		}
+		buf.WriteByte('[')
+		for i, elem := range v {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			newline(depth + 1)
+			if err := write(buf, elem, indent, depth+1); err != nil {
+				return err
+			}
+		}
+		newline(depth)
+		buf.WriteByte(']')
+	case string:
+		writeString(buf, v)
+	case json.Number:
+		buf.WriteString(v.String())
+	case bool:
+		if v {
+			buf.WriteString("true")
+		} else {
+			buf.WriteString("false")
+		}
+	case nil:
+		buf.WriteString("null")
+	default:
+		return fmt.Errorf("pjson: unexpected JSON value %T", v)
+	}
+	return nil
+}
+
+func writeString(buf *bytes.Buffer, s string) {
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+	// Encode always appends a newline.
+	buf.Truncate(buf.Len() - 1)
+}
@@ -0,0 +1,238 @@
+package pjson
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// Minimal copies of the Penumbra messages with special JSON forms, and a
+// container that uses them in every position the walker handles.
+var testFiles = []string{`
+name: "penumbra/core/keys/v1/keys.proto"
+package: "penumbra.core.keys.v1"
+syntax: "proto3"
+message_type: {
+  name: "Address"
+  field: {name: "inner" number: 1 type: TYPE_BYTES label: LABEL_OPTIONAL json_name: "inner"}
+  field: {name: "alt_bech32m" number: 2 type: TYPE_STRING label: LABEL_OPTIONAL json_name: "altBech32m"}
+}`, `
+name: "penumbra/core/asset/v1/asset.proto"
+package: "penumbra.core.asset.v1"
+syntax: "proto3"
+message_type: {
+  name: "AssetId"
+  field: {name: "inner" number: 1 type: TYPE_BYTES label: LABEL_OPTIONAL json_name: "inner"}
+  field: {name: "alt_bech32m" number: 2 type: TYPE_STRING label: LABEL_OPTIONAL json_name: "altBech32m"}
+  field: {name: "alt_base_denom" number: 3 type: TYPE_STRING label: LABEL_OPTIONAL json_name: "altBaseDenom"}
+}`, `
+name: "penumbra/core/num/v1/num.proto"
+package: "penumbra.core.num.v1"
+syntax: "proto3"
+message_type: {
+  name: "Amount"
+  field: {name: "lo" number: 1 type: TYPE_UINT64 label: LABEL_OPTIONAL json_name: "lo"}
+  field: {name: "hi" number: 2 type: TYPE_UINT64 label: LABEL_OPTIONAL json_name: "hi"}
+}`, `
+name: "pjsontest/v1/test.proto"
+package: "pjsontest.v1"
+syntax: "proto3"
+dependency: ["penumbra/core/keys/v1/keys.proto", "penumbra/core/asset/v1/asset.proto", "penumbra/core/num/v1/num.proto", "google/protobuf/any.proto"]
+message_type: {
+  name: "Wallet"
+  field: {name: "address" number: 1 type: TYPE_MESSAGE type_name: ".penumbra.core.keys.v1.Address" label: LABEL_OPTIONAL json_name: "address"}
+  field: {name: "asset_id" number: 2 type: TYPE_MESSAGE type_name: ".penumbra.core.asset.v1.AssetId" label: LABEL_OPTIONAL json_name: "assetId"}
+  field: {name: "amount" number: 3 type: TYPE_MESSAGE type_name: ".penumbra.core.num.v1.Amount" label: LABEL_OPTIONAL json_name: "amount"}
+  field: {name: "amounts" number: 4 type: TYPE_MESSAGE type_name: ".penumbra.core.num.v1.Amount" label: LABEL_REPEATED json_name: "amounts"}
+  field: {name: "balances" number: 5 type: TYPE_MESSAGE type_name: ".pjsontest.v1.Wallet.BalancesEntry" label: LABEL_REPEATED json_name: "balances"}
+  field: {name: "payload" number: 6 type: TYPE_MESSAGE type_name: ".google.protobuf.Any" label: LABEL_OPTIONAL json_name: "payload"}
+  field: {name: "memo" number: 7 type: TYPE_STRING label: LABEL_OPTIONAL json_name: "memo"}
+  nested_type: {
+    name: "BalancesEntry"
+    field: {name: "key" number: 1 type: TYPE_STRING label: LABEL_OPTIONAL json_name: "key"}
+    field: {name: "value" number: 2 type: TYPE_MESSAGE type_name: ".penumbra.core.num.v1.Amount" label: LABEL_OPTIONAL json_name: "value"}
+    options: {map_entry: true}
+  }
+}`}
+
+const (
+	testAddress = "penumbra147mfall0zr6am5r45qkwht7xqqrdsp50czde7empv7yq2nk3z8yyfh9k9520ddgswkmzar22vhz9dwtuem7uxw0qytfpv7lk3q9dp8ccaw2fn5c838rfackazmgf3ahh09cxmz"
+	// passet1 encoding of the 32 bytes 0x00..0x1f.
+	testAssetB64 = "AAECAwQFBgcICQoLDA0ODxAREhMUFRYXGBkaGxwdHh8="
+)
+
+func testTypes(t testing.TB) (protoreflect.MessageDescriptor, *dynamicpb.Types) {
+	t.Helper()
+	files := new(protoregistry.Files)
+	if err := files.RegisterFile(anypb.File_google_protobuf_any_proto); err != nil {
+		t.Fatal(err)
+	}
+	for _, text := range testFiles {
+		fdp := new(descriptorpb.FileDescriptorProto)
+		if err := prototext.Unmarshal([]byte(text), fdp); err != nil {
+			t.Fatal(err)
+		}
+		fd, err := protodesc.NewFile(fdp, files)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := files.RegisterFile(fd); err != nil {
+			t.Fatal(err)
+		}
+	}
+	d, err := files.FindDescriptorByName("pjsontest.v1.Wallet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d.(protoreflect.MessageDescriptor), dynamicpb.NewTypes(files)
+}
+
+// canonical builds a message from its protojson form.
+func canonical(t *testing.T, md protoreflect.MessageDescriptor, types *dynamicpb.Types, s string) proto.Message {
+	t.Helper()
+	m := dynamicpb.NewMessage(md)
+	if err := (protojson.UnmarshalOptions{Resolver: types}).Unmarshal([]byte(s), m); err != nil {
+		t.Fatalf("protojson.Unmarshal(%s): %v", s, err)
+	}
+	return m
+}
+
+func TestMarshal(t *testing.T) {
+	md, types := testTypes(t)
+	in := `{
+		"assetId": {"inner": "` + testAssetB64 + `"},
+		"amount": {"lo": "5", "hi": "1"},
+		"amounts": [{}, {"lo": "1000000"}],
+		"balances": {"gm": {"lo": "42"}},
+		"payload": {"@type": "type.googleapis.com/pjsontest.v1.Wallet", "amount": {"lo": "7"}},
+		"memo": "<ok>"
+	}`
+	m := canonical(t, md, types, in)
+
+	got, err := MarshalOptions{protojson.MarshalOptions{Resolver: types}}.Marshal(m)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	want := `{"assetId":"passet1qqqsyqcyq5rqwzqfpg9scrgwpugpzysnzs23v9ccrydpk8qarc0s0ur7kn",` +
+		`"amount":"18446744073709551621",` +
+		`"amounts":["0","1000000"],` +
+		`"balances":{"gm":"42"},` +
+		`"payload":{"@type":"type.googleapis.com/pjsontest.v1.Wallet","amount":"7"},` +
+		`"memo":"<ok>"}`
+	if string(got) != want {
+		t.Fatalf("Marshal =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	md, types := testTypes(t)
+	in := `{
+		"address": {"altBech32m": "unused"},
+		"assetId": {"inner": "` + testAssetB64 + `"},
+		"amount": {"lo": "18446744073709551615", "hi": "18446744073709551615"},
+		"balances": {"a": {}, "b": {"hi": "3"}}
+	}`
+	m := canonical(t, md, types, in)
+	b, err := MarshalOptions{protojson.MarshalOptions{Resolver: types, Multiline: true}}.Marshal(m)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	if !json.Valid(b) || !strings.Contains(string(b), "\n  \"amount\": \"340282366920938463463374607431768211455\"") {
+		t.Fatalf("Marshal with Multiline =\n%s", b)
+	}
+
+	out := dynamicpb.NewMessage(md)
+	if err := (UnmarshalOptions{protojson.UnmarshalOptions{Resolver: types}}).Unmarshal(b, out); err != nil {
+		t.Fatalf("Unmarshal(%s): %v", b, err)
+	}
+	if !proto.Equal(m, out) {
+		t.Fatalf("round trip = %v, want %v", out, m)
+	}
+}
+
+func TestUnmarshalAddress(t *testing.T) {
+	md, types := testTypes(t)
+	opts := UnmarshalOptions{protojson.UnmarshalOptions{Resolver: types}}
+
+	m := dynamicpb.NewMessage(md)
+	if err := opts.Unmarshal([]byte(`{"address": "`+testAddress+`"}`), m); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	addr := m.Get(md.Fields().ByName("address")).Message()
+	inner := addr.Get(addr.Descriptor().Fields().ByName("inner")).Bytes()
+	if len(inner) != 80 {
+		t.Fatalf("address inner = %d bytes, want 80", len(inner))
+	}
+	b, err := MarshalOptions{protojson.MarshalOptions{Resolver: types}}.Marshal(m)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	if want := `{"address":"` + testAddress + `"}`; string(b) != want {
+		t.Fatalf("Marshal = %s, want %s", b, want)
+	}
+}
+
+func TestUnmarshalCanonical(t *testing.T) {
+	md, types := testTypes(t)
+	in := `{"amount": {"lo": "5", "hi": "1"}, "assetId": {"inner": "` + testAssetB64 + `"}}`
+	m := dynamicpb.NewMessage(md)
+	if err := (UnmarshalOptions{protojson.UnmarshalOptions{Resolver: types}}).Unmarshal([]byte(in), m); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if want := canonical(t, md, types, in); !proto.Equal(m, want) {
+		t.Fatalf("Unmarshal = %v, want %v", m, want)
+	}
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	md, types := testTypes(t)
+	opts := UnmarshalOptions{protojson.UnmarshalOptions{Resolver: types}}
+	for _, in := range []string{
+		`{"address": "passet1qqqsyqcyq5rqwzqfpg9scrgwpugpzysnzs23v9ccrydpk8qarc0s0ur7kn"}`,
+		`{"assetId": "passet1invalid"}`,
+		`{"amount": "-1"}`,
+		`{"amount": "1.5"}`,
+		`{"amount": "340282366920938463463374607431768211456"}`,
+		`{"amount": "1"} trailing`,
+	} {
+		if err := opts.Unmarshal([]byte(in), dynamicpb.NewMessage(md)); err == nil {
+			t.Errorf("Unmarshal(%s): want error", in)
+		}
+	}
+}
+
+func FuzzUnmarshal(f *testing.F) {
+	f.Add(`{"address": "` + testAddress + `"}`)
+	f.Add(`{"assetId": {"inner": "` + testAssetB64 + `"}, "amount": "340282366920938463463374607431768211455"}`)
+	f.Add(`{"amounts": ["0", {"lo": "1"}], "balances": {"a": "7"}}`)
+	f.Add(`{"payload": {"@type": "type.googleapis.com/pjsontest.v1.Wallet", "amount": "1"}}`)
+	md, types := testTypes(f)
+	f.Fuzz(func(t *testing.T, s string) {
+		m := dynamicpb.NewMessage(md)
+		if err := (UnmarshalOptions{protojson.UnmarshalOptions{Resolver: types}}).Unmarshal([]byte(s), m); err != nil {
+			return
+		}
+		// Anything Unmarshal accepts must survive a round trip.
+		b, err := MarshalOptions{protojson.MarshalOptions{Resolver: types}}.Marshal(m)
+		if err != nil {
+			t.Fatalf("Marshal after Unmarshal(%s): %v", s, err)
+		}
+		out := dynamicpb.NewMessage(md)
+		if err := (UnmarshalOptions{protojson.UnmarshalOptions{Resolver: types}}).Unmarshal(b, out); err != nil {
+			t.Fatalf("Unmarshal(%s) after round trip: %v", b, err)
+		}
+		if !proto.Equal(m, out) {
+			t.Fatalf("round trip of %s = %v, want %v", s, out, m)
+		}
+	})
+}
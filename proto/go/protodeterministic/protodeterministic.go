@@ -0,0 +1,73 @@
+// Package protodeterministic marshals messages to stable bytes, for use
+// wherever a digest or signature is computed over an encoded message.
+//
+// Penumbra's effect hashes are BLAKE2b digests over the prost encoding of a
+// message (see crates/core/txhash). prost writes fields in field number
+// order, omits proto3 default values, and drops unknown fields when decoding.
+// proto.Marshal makes none of these guarantees about map order, and keeps
+// unknown fields, so a message that passed through a peer with a newer schema
+// would hash differently. Marshal sorts map entries by key and discards
+// unknown fields, without modifying its argument.
+package protodeterministic
+
+import (
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+var opts = proto.MarshalOptions{Deterministic: true}
+
+// Marshal returns the deterministic encoding of m.
+func Marshal(m proto.Message) ([]byte, error) {
+	return MarshalAppend(nil, m)
+}
+
+// MarshalAppend appends the deterministic encoding of m to b.
+func MarshalAppend(b []byte, m proto.Message) ([]byte, error) {
+	if walk(m.ProtoReflect(), hasUnknown) {
+		m = proto.Clone(m)
+		walk(m.ProtoReflect(), discardUnknown)
+	}
+	return opts.MarshalAppend(b, m)
+}
+
+func hasUnknown(m protoreflect.Message) bool {
+	return len(m.GetUnknown()) > 0
+}
+
+func discardUnknown(m protoreflect.Message) bool {
+	if len(m.GetUnknown()) > 0 {
+		m.SetUnknown(nil)
+	}
+	return false
+}
+
+// walk calls f on m and every message nested in it, stopping early and
+// returning true as soon as f does.
+func walk(m protoreflect.Message, f func(protoreflect.Message) bool) bool {
+	if f(m) {
+		return true
+	}
+	stop := false
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		switch {
+		case fd.IsMap():
+			if fd.MapValue().Message() != nil {
+				v.Map().Range(func(_ protoreflect.MapKey, v protoreflect.Value) bool {
+					stop = walk(v.Message(), f)
+					return !stop
+				})
+			}
+		case fd.Message() == nil:
+		case fd.IsList():
+			l := v.List()
+			for i := 0; i < l.Len() && !stop; i++ {
+				stop = walk(l.Get(i).Message(), f)
+			}
+		default:
+			stop = walk(v.Message(), f)
+		}
+		return !stop
+	})
+	return stop
+}
@@ -0,0 +1,99 @@
+package protodeterministic
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestMarshalSortsMapEntries(t *testing.T) {
+	forward := &structpb.Struct{Fields: map[string]*structpb.Value{}}
+	backward := &structpb.Struct{Fields: map[string]*structpb.Value{}}
+	for i := 0; i < 64; i++ {
+		forward.Fields[fmt.Sprintf("k%02d", i)] = structpb.NewNumberValue(float64(i))
+	}
+	for i := 63; i >= 0; i-- {
+		backward.Fields[fmt.Sprintf("k%02d", i)] = structpb.NewNumberValue(float64(i))
+	}
+
+	want, err := Marshal(forward)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	for i := 0; i < 16; i++ {
+		got, err := Marshal(backward)
+		if err != nil {
+			t.Fatalf("Marshal: %v", err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Fatalf("Marshal is not deterministic:\n%x\n%x", got, want)
+		}
+	}
+
+	var decoded structpb.Struct
+	if err := proto.Unmarshal(want, &decoded); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if !proto.Equal(&decoded, forward) {
+		t.Fatalf("round trip = %v, want %v", &decoded, forward)
+	}
+}
+
+func TestMarshalAppend(t *testing.T) {
+	m, err := structpb.NewStruct(map[string]any{"a": 1, "b": "two"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := MarshalAppend([]byte("prefix"), m)
+	if err != nil {
+		t.Fatalf("MarshalAppend: %v", err)
+	}
+	if !bytes.Equal(got, append([]byte("prefix"), want...)) {
+		t.Fatalf("MarshalAppend = %x, want prefix followed by %x", got, want)
+	}
+}
+
+func TestMarshalDiscardsUnknownFields(t *testing.T) {
+	clean, err := structpb.NewStruct(map[string]any{
+		"list":   []any{"x", map[string]any{"y": true}},
+		"nested": map[string]any{"z": 3},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := Marshal(clean)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	unknown := protowire.AppendVarint(protowire.AppendTag(nil, 99, protowire.VarintType), 1)
+	dirty := proto.Clone(clean).(*structpb.Struct)
+	dirty.ProtoReflect().SetUnknown(unknown)
+	dirty.Fields["nested"].GetStructValue().ProtoReflect().SetUnknown(unknown)
+	dirty.Fields["list"].GetListValue().Values[1].ProtoReflect().SetUnknown(unknown)
+
+	got, err := Marshal(dirty)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatalf("Marshal kept unknown fields:\n%x\nwant\n%x", got, want)
+	}
+	for _, m := range []proto.Message{
+		dirty,
+		dirty.Fields["nested"].GetStructValue(),
+		dirty.Fields["list"].GetListValue().Values[1],
+	} {
+		if len(m.ProtoReflect().GetUnknown()) == 0 {
+			t.Fatal("Marshal modified its argument")
+		}
+	}
+}
@@ -0,0 +1,126 @@
+// Package protoenum gives generated enums a short, stable text form for
+// config files, flags and APIs.
+//
+// Penumbra enum values carry their enum's name as a prefix, as required by
+// buf's ENUM_VALUE_PREFIX lint rule, so Vote_VOTE_YES has the protobuf name
+// VOTE_YES. Its text form drops the prefix and is lowercase, matching the
+// Rust Display implementations: "yes". Parse accepts the text form, the
+// protobuf name in any case, and the number.
+//
+//	vote, err := protoenum.Parse[governancev1.Vote]("yes")
+//	protoenum.String(governancev1.Vote_VOTE_YES) // "yes"
+//
+// Text wraps an enum so that it encodes as its text form wherever an
+// encoding.TextMarshaler is used, including encoding/json, TOML and YAML
+// libraries, and flag.TextVar:
+//
+//	type Config struct {
+//		Vote protoenum.Text[governancev1.Vote] `json:"vote"`
+//	}
+package protoenum
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Enum is the constraint satisfied by generated enum types.
+type Enum interface {
+	~int32
+	protoreflect.Enum
+}
+
+// prefix returns the prefix shared by the values of ed: its name in upper
+// snake case, followed by an underscore.
+func prefix(ed protoreflect.EnumDescriptor) string {
+	name := string(ed.Name())
+	var b strings.Builder
+	for i, r := range name {
+		if i > 0 && r >= 'A' && r <= 'Z' {
+			prev := name[i-1]
+			if prev >= 'a' && prev <= 'z' || prev >= '0' && prev <= '9' {
+				b.WriteByte('_')
+			}
+		}
+		b.WriteRune(r)
+	}
+	return strings.ToUpper(b.String()) + "_"
+}
+
+// String returns the text form of e. Values not declared by the enum are
+// formatted as their number.
+func String[E Enum](e E) string {
+	ed := e.Descriptor()
+	vd := ed.Values().ByNumber(protoreflect.EnumNumber(e))
+	if vd == nil {
+		return strconv.Itoa(int(e))
+	}
+	name := string(vd.Name())
+	if short, ok := strings.CutPrefix(name, prefix(ed)); ok {
+		name = short
+	}
+	return strings.ToLower(name)
+}
+
+// Parse returns the value of E named by s, in its text form, as a protobuf
+// value name, or as a number. Names are matched without regard to case.
+// Numbers must be declared by the enum.
+func Parse[E Enum](s string) (E, error) {
+	var zero E
+	ed := zero.Descriptor()
+	values := ed.Values()
+	if n, err := strconv.ParseInt(s, 10, 32); err == nil {
+		if values.ByNumber(protoreflect.EnumNumber(n)) != nil {
+			return E(n), nil
+		}
+		return zero, fmt.Errorf("protoenum: %d is not a value of %s", n, ed.FullName())
+	}
+	upper := strings.ToUpper(s)
+	for _, name := range []string{upper, prefix(ed) + upper} {
+		if vd := values.ByName(protoreflect.Name(name)); vd != nil {
+			return E(vd.Number()), nil
+		}
+	}
+	return zero, fmt.Errorf("protoenum: %q is not a value of %s", s, ed.FullName())
+}
+
+// Values returns the text forms of the values of E, in declaration order,
+// for help text and validation messages.
+func Values[E Enum]() []string {
+	var zero E
+	values := zero.Descriptor().Values()
+	out := make([]string, values.Len())
+	for i := range out {
+		out[i] = String(E(values.Get(i).Number()))
+	}
+	return out
+}
+
+// Text holds an enum value and marshals it as its text form.
+type Text[E Enum] struct {
+	Value E
+}
+
+// String returns the text form of t.Value.
+func (t Text[E]) String() string {
+	return String(t.Value)
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (t Text[E]) MarshalText() ([]byte, error) {
+	return []byte(String(t.Value)), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler. It accepts every form
+// Parse does.
+func (t *Text[E]) UnmarshalText(b []byte) error {
+	v, err := Parse[E](string(b))
+	if err != nil {
+		return err
+	}
+	t.Value = v
+	return nil
+}
@@ -0,0 +1,78 @@
+package protoenum
+
+import (
+	"encoding/json"
+	"flag"
+	"slices"
+	"testing"
+
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/known/typepb"
+)
+
+func TestString(t *testing.T) {
+	for _, tt := range []struct {
+		got, want string
+	}{
+		{String(typepb.Syntax_SYNTAX_PROTO3), "proto3"},
+		{String(typepb.Syntax(42)), "42"},
+		// Values without the enum's prefix keep their full name.
+		{String(typepb.Field_TYPE_STRING), "type_string"},
+		// Multi-word enum names.
+		{String(descriptorpb.FeatureSet_ENUM_TYPE_UNKNOWN), "unknown"},
+	} {
+		if tt.got != tt.want {
+			t.Errorf("String = %q, want %q", tt.got, tt.want)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	for _, s := range []string{"proto3", "PROTO3", "SYNTAX_PROTO3", "syntax_proto3", "1"} {
+		if got, err := Parse[typepb.Syntax](s); err != nil || got != typepb.Syntax_SYNTAX_PROTO3 {
+			t.Errorf("Parse(%q) = %v, %v", s, got, err)
+		}
+	}
+	if got, err := Parse[descriptorpb.FeatureSet_EnumType]("closed"); err != nil || got != descriptorpb.FeatureSet_CLOSED {
+		t.Errorf("Parse(closed) = %v, %v", got, err)
+	}
+	for _, s := range []string{"", "proto4", "42", "-1"} {
+		if _, err := Parse[typepb.Syntax](s); err == nil {
+			t.Errorf("Parse(%q): want error", s)
+		}
+	}
+}
+
+func TestValues(t *testing.T) {
+	want := []string{"proto2", "proto3", "editions"}
+	if got := Values[typepb.Syntax](); !slices.Equal(got, want) {
+		t.Errorf("Values = %q, want %q", got, want)
+	}
+}
+
+func TestText(t *testing.T) {
+	type config struct {
+		Syntax Text[typepb.Syntax] `json:"syntax"`
+	}
+	b, err := json.Marshal(config{Text[typepb.Syntax]{typepb.Syntax_SYNTAX_EDITIONS}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"syntax":"editions"}`; string(b) != want {
+		t.Errorf("json.Marshal = %s, want %s", b, want)
+	}
+	var c config
+	if err := json.Unmarshal([]byte(`{"syntax":"SYNTAX_PROTO2"}`), &c); err != nil || c.Syntax.Value != typepb.Syntax_SYNTAX_PROTO2 {
+		t.Errorf("json.Unmarshal = %v, %v", c, err)
+	}
+	if err := json.Unmarshal([]byte(`{"syntax":"proto4"}`), &c); err == nil {
+		t.Error("json.Unmarshal(proto4): want error")
+	}
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	var v Text[typepb.Syntax]
+	fs.TextVar(&v, "syntax", Text[typepb.Syntax]{typepb.Syntax_SYNTAX_PROTO3}, "syntax")
+	if err := fs.Parse([]string{"-syntax", "editions"}); err != nil || v.Value != typepb.Syntax_SYNTAX_EDITIONS {
+		t.Errorf("flag = %v, %v", v, err)
+	}
+}
@@ -0,0 +1,250 @@
+// Package rpcauth authenticates and authorizes callers of the custody and
+// view services.
+//
+// The services carry no authentication of their own, so a custody server
+// that is reachable over the network will sign for anyone. A Policy maps
+// each method, or each service, to an Authorizer that checks the caller:
+//
+//	policy := rpcauth.Policy{
+//		Methods: map[string]rpcauth.Authorizer{
+//			// Only the wallet's frontend may request signatures.
+//			"penumbra.custody.v1.CustodyService": rpcauth.ClientCert("wallet"),
+//			// Read-only queries need a token.
+//			"penumbra.view.v1.ViewService": rpcauth.Token(os.Getenv("VIEW_TOKEN")),
+//		},
+//	}
+//	tlsConfig, err := rpcauth.ServerTLSConfig("server.pem", "server.key", "clients-ca.pem")
+//	s := grpc.NewServer(append(policy.ServerOptions(),
+//		grpc.Creds(credentials.NewTLS(tlsConfig)))...)
+//
+// Methods not covered by the policy are denied, so a service added to the
+// server later is not exposed by accident.
+package rpcauth
+
+import (
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// An Authorizer returns nil if the caller in ctx may call fullMethod, and a
+// gRPC status error otherwise.
+type Authorizer func(ctx context.Context, fullMethod string) error
+
+// Allow permits every caller. Use it for methods that are public, such as
+// health checks.
+func Allow(context.Context, string) error { return nil }
+
+// Deny rejects every caller.
+func Deny(_ context.Context, fullMethod string) error {
+	return status.Errorf(codes.PermissionDenied, "rpcauth: %s is not permitted", fullMethod)
+}
+
+// Token permits callers that send one of the tokens as a bearer token in
+// the authorization metadata. Empty tokens are ignored, so a Token built
+// from an unset environment variable admits no one.
+func Token(tokens ...string) Authorizer {
+	var valid [][]byte
+	for _, t := range tokens {
+		if t != "" {
+			valid = append(valid, []byte(t))
+		}
+	}
+	return func(ctx context.Context, fullMethod string) error {
+		md, _ := metadata.FromIncomingContext(ctx)
+		for _, v := range md.Get("authorization") {
+			scheme, token, ok := strings.Cut(v, " ")
+			if !ok || !strings.EqualFold(scheme, "bearer") {
+				continue
+			}
+			for _, t := range valid {
+				if subtle.ConstantTimeCompare([]byte(token), t) == 1 {
+					return nil
+				}
+			}
+		}
+		return status.Errorf(codes.Unauthenticated, "rpcauth: %s requires a valid bearer token", fullMethod)
+	}
+}
+
+// ClientCert permits callers that presented a client certificate verified
+// by the server's TLS configuration, whose common name or one of whose DNS
+// names is in names. With no names, any verified certificate is permitted.
+func ClientCert(names ...string) Authorizer {
+	return func(ctx context.Context, fullMethod string) error {
+		p, ok := peer.FromContext(ctx)
+		if !ok {
+			return status.Errorf(codes.Unauthenticated, "rpcauth: %s requires a client certificate", fullMethod)
+		}
+		info, ok := p.AuthInfo.(credentials.TLSInfo)
+		if !ok || len(info.State.VerifiedChains) == 0 {
+			return status.Errorf(codes.Unauthenticated, "rpcauth: %s requires a client certificate", fullMethod)
+		}
+		if len(names) == 0 {
+			return nil
+		}
+		leaf := info.State.VerifiedChains[0][0]
+		if slices.Contains(names, leaf.Subject.CommonName) {
+			return nil
+		}
+		for _, n := range leaf.DNSNames {
+			if slices.Contains(names, n) {
+				return nil
+			}
+		}
+		return status.Errorf(codes.PermissionDenied, "rpcauth: certificate %q may not call %s", leaf.Subject.CommonName, fullMethod)
+	}
+}
+
+// AnyOf permits callers that any of the authorizers permits. If all of them
+// reject the caller, the first error is returned.
+func AnyOf(authorizers ...Authorizer) Authorizer {
+	return func(ctx context.Context, fullMethod string) error {
+		var first error
+		for _, a := range authorizers {
+			err := a(ctx, fullMethod)
+			if err == nil {
+				return nil
+			}
+			if first == nil {
+				first = err
+			}
+		}
+		if first == nil {
+			return Deny(ctx, fullMethod)
+		}
+		return first
+	}
+}
+
+// Policy selects the Authorizer for each RPC.
+type Policy struct {
+	// Methods maps a full method name ("/penumbra.custody.v1.CustodyService/Authorize")
+	// or a service name ("penumbra.custody.v1.CustodyService") to the
+	// Authorizer for it. A method entry takes precedence over its service.
+	Methods map[string]Authorizer
+	// Default authorizes methods with no entry in Methods. If nil, they are
+	// denied.
+	Default Authorizer
+}
+
+// Authorize checks the caller in ctx against the Authorizer for fullMethod.
+func (p Policy) Authorize(ctx context.Context, fullMethod string) error {
+	if a, ok := p.Methods[fullMethod]; ok {
+		return a(ctx, fullMethod)
+	}
+	service, _, _ := strings.Cut(strings.TrimPrefix(fullMethod, "/"), "/")
+	if a, ok := p.Methods[service]; ok {
+		return a(ctx, fullMethod)
+	}
+	if p.Default != nil {
+		return p.Default(ctx, fullMethod)
+	}
+	return Deny(ctx, fullMethod)
+}
+
+// ServerOptions returns the interceptors that enforce the policy before any
+// handler runs.
+func (p Policy) ServerOptions() []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+			if err := p.Authorize(ctx, info.FullMethod); err != nil {
+				return nil, err
+			}
+			return handler(ctx, req)
+		}),
+		grpc.ChainStreamInterceptor(func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			if err := p.Authorize(ss.Context(), info.FullMethod); err != nil {
+				return err
+			}
+			return handler(srv, ss)
+		}),
+	}
+}
+
+// ServerTLSConfig returns a TLS configuration for a server with the given
+// certificate and key. If clientCAFile is not empty, clients must present a
+// certificate signed by one of the CAs in it.
+func ServerTLSConfig(certFile, keyFile, clientCAFile string) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("rpcauth: %w", err)
+	}
+	config := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS13,
+	}
+	if clientCAFile != "" {
+		pool, err := loadPool(clientCAFile)
+		if err != nil {
+			return nil, err
+		}
+		config.ClientCAs = pool
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return config, nil
+}
+
+// ClientTLSConfig returns a TLS configuration for a client that trusts the
+// CAs in caFile, or the system roots if it is empty. If certFile is not
+// empty, the client presents that certificate.
+func ClientTLSConfig(caFile, certFile, keyFile string) (*tls.Config, error) {
+	config := &tls.Config{MinVersion: tls.VersionTLS13}
+	if caFile != "" {
+		pool, err := loadPool(caFile)
+		if err != nil {
+			return nil, err
+		}
+		config.RootCAs = pool
+	}
+	if certFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("rpcauth: %w", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
+
+func loadPool(file string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("rpcauth: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("rpcauth: no certificates in %s", file)
+	}
+	return pool, nil
+}
+
+// BearerToken returns credentials that send token as a bearer token on
+// every RPC. Unless insecure is set, grpc refuses to dial with them over a
+// connection without transport security.
+func BearerToken(token string, insecure bool) credentials.PerRPCCredentials {
+	return bearerToken{token: token, insecure: insecure}
+}
+
+type bearerToken struct {
+	token    string
+	insecure bool
+}
+
+func (b bearerToken) GetRequestMetadata(context.Context, ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer " + b.token}, nil
+}
+
+func (b bearerToken) RequireTransportSecurity() bool { return !b.insecure }
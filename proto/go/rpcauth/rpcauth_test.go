@@ -0,0 +1,170 @@
+package rpcauth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/penumbra-zone/penumbra/proto/go/inprocess"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+func newHealth(t *testing.T, serverOpts []grpc.ServerOption, dialOpts ...grpc.DialOption) healthpb.HealthClient {
+	t.Helper()
+	srv := health.NewServer()
+	conn, err := inprocess.New(func(s grpc.ServiceRegistrar) {
+		healthpb.RegisterHealthServer(s, srv)
+	}, serverOpts, dialOpts...)
+	if err != nil {
+		t.Fatalf("inprocess.New: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return healthpb.NewHealthClient(conn)
+}
+
+func check(client healthpb.HealthClient) codes.Code {
+	_, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{})
+	return status.Code(err)
+}
+
+func TestToken(t *testing.T) {
+	policy := Policy{Methods: map[string]Authorizer{"grpc.health.v1.Health": Token("", "s3cret")}}
+	for _, tt := range []struct {
+		name  string
+		opts  []grpc.DialOption
+		token string
+		want  codes.Code
+	}{
+		{name: "none", want: codes.Unauthenticated},
+		{name: "empty", opts: []grpc.DialOption{grpc.WithPerRPCCredentials(BearerToken("", true))}, want: codes.Unauthenticated},
+		{name: "wrong", opts: []grpc.DialOption{grpc.WithPerRPCCredentials(BearerToken("guess", true))}, want: codes.Unauthenticated},
+		{name: "valid", opts: []grpc.DialOption{grpc.WithPerRPCCredentials(BearerToken("s3cret", true))}, want: codes.OK},
+	} {
+		if got := check(newHealth(t, policy.ServerOptions(), tt.opts...)); got != tt.want {
+			t.Errorf("%s: Check = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPolicy(t *testing.T) {
+	policy := Policy{Methods: map[string]Authorizer{
+		"/grpc.health.v1.Health/Check": Allow,
+		"grpc.health.v1.Health":        Deny,
+	}}
+	client := newHealth(t, policy.ServerOptions())
+	if got := check(client); got != codes.OK {
+		t.Errorf("Check = %v, want OK", got)
+	}
+	stream, err := client.Watch(context.Background(), &healthpb.HealthCheckRequest{})
+	if err == nil {
+		_, err = stream.Recv()
+	}
+	if status.Code(err) != codes.PermissionDenied {
+		t.Errorf("Watch = %v, want PermissionDenied", err)
+	}
+
+	// Methods not in the policy are denied unless there is a Default.
+	if got := check(newHealth(t, Policy{}.ServerOptions())); got != codes.PermissionDenied {
+		t.Errorf("Check with empty policy = %v, want PermissionDenied", got)
+	}
+	if got := check(newHealth(t, Policy{Default: Allow}.ServerOptions())); got != codes.OK {
+		t.Errorf("Check with Default = %v, want OK", got)
+	}
+}
+
+func TestClientCert(t *testing.T) {
+	dir := t.TempDir()
+	ca, caKey := newCert(t, dir, "ca", nil, nil)
+	newCert(t, dir, "inprocess", ca, caKey)
+	newCert(t, dir, "wallet", ca, caKey)
+	newCert(t, dir, "other", ca, caKey)
+	file := func(name string) string { return filepath.Join(dir, name) }
+
+	serverTLS, err := ServerTLSConfig(file("inprocess.pem"), file("inprocess.key"), file("ca.pem"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	policy := Policy{Methods: map[string]Authorizer{
+		"grpc.health.v1.Health": AnyOf(ClientCert("wallet"), Token("s3cret")),
+	}}
+	serverOpts := append(policy.ServerOptions(), grpc.Creds(credentials.NewTLS(serverTLS)))
+
+	for _, tt := range []struct {
+		cert string
+		opts []grpc.DialOption
+		want codes.Code
+	}{
+		{cert: "wallet", want: codes.OK},
+		{cert: "other", want: codes.PermissionDenied},
+		{cert: "other", opts: []grpc.DialOption{grpc.WithPerRPCCredentials(BearerToken("s3cret", false))}, want: codes.OK},
+	} {
+		clientTLS, err := ClientTLSConfig(file("ca.pem"), file(tt.cert+".pem"), file(tt.cert+".key"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		opts := append([]grpc.DialOption{grpc.WithTransportCredentials(credentials.NewTLS(clientTLS))}, tt.opts...)
+		if got := check(newHealth(t, serverOpts, opts...)); got != tt.want {
+			t.Errorf("%s: Check = %v, want %v", tt.cert, got, tt.want)
+		}
+	}
+}
+
+// newCert writes name.pem and name.key to dir. The certificate is a CA if
+// parent is nil, and is signed by parent otherwise.
+func newCert(t *testing.T, dir, name string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	if parent == nil {
+		tmpl.IsCA = true
+		tmpl.BasicConstraintsValid = true
+		tmpl.KeyUsage = x509.KeyUsageCertSign
+		parent, parentKey = tmpl, key
+	} else {
+		tmpl.DNSNames = []string{name}
+		tmpl.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth}
+		tmpl.KeyUsage = x509.KeyUsageDigitalSignature
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	write := func(file, typ string, b []byte) {
+		if err := os.WriteFile(filepath.Join(dir, file), pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: b}), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(name+".pem", "CERTIFICATE", der)
+	write(name+".key", "EC PRIVATE KEY", keyDER)
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, key
+}
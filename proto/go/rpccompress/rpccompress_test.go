@@ -0,0 +1,163 @@
+package rpccompress
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/penumbra-zone/penumbra/proto/go/inprocess"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/encoding"
+	testgrpc "google.golang.org/grpc/interop/grpc_testing"
+	"google.golang.org/grpc/stats"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// server echoes the request payload back in every streamed response.
+type server struct {
+	testgrpc.UnimplementedTestServiceServer
+}
+
+// encodings is a stats.Handler recording the compression of the requests
+// to each method.
+type encodings struct {
+	mu sync.Mutex
+	m  map[string]string
+}
+
+func (e *encodings) TagRPC(ctx context.Context, _ *stats.RPCTagInfo) context.Context   { return ctx }
+func (e *encodings) TagConn(ctx context.Context, _ *stats.ConnTagInfo) context.Context { return ctx }
+func (e *encodings) HandleConn(context.Context, stats.ConnStats)                       {}
+
+func (e *encodings) HandleRPC(_ context.Context, s stats.RPCStats) {
+	if h, ok := s.(*stats.InHeader); ok {
+		e.mu.Lock()
+		defer e.mu.Unlock()
+		e.m[h.FullMethod] = h.Compression
+	}
+}
+
+func (e *encodings) get(method string) string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.m[method]
+}
+
+func (s *server) UnaryCall(ctx context.Context, req *testgrpc.SimpleRequest) (*testgrpc.SimpleResponse, error) {
+	return &testgrpc.SimpleResponse{Payload: req.GetPayload()}, nil
+}
+
+func (s *server) StreamingOutputCall(req *testgrpc.StreamingOutputCallRequest, stream testgrpc.TestService_StreamingOutputCallServer) error {
+	for range 3 {
+		if err := stream.Send(&testgrpc.StreamingOutputCallResponse{Payload: req.GetPayload()}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestDialOption(t *testing.T) {
+	for _, name := range []string{Gzip, Zstd} {
+		t.Run(name, func(t *testing.T) {
+			enc := &encodings{m: map[string]string{}}
+			conn, err := inprocess.New(func(s grpc.ServiceRegistrar) {
+				testgrpc.RegisterTestServiceServer(s, &server{})
+			}, []grpc.ServerOption{grpc.StatsHandler(enc)}, DialOption(name, "/grpc.testing.TestService/StreamingOutputCall"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+			client := testgrpc.NewTestServiceClient(conn)
+			payload := &testgrpc.Payload{Body: bytes.Repeat([]byte("penumbra"), 1000)}
+
+			stream, err := client.StreamingOutputCall(context.Background(), &testgrpc.StreamingOutputCallRequest{Payload: payload})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var n int
+			for {
+				resp, err := stream.Recv()
+				if err == io.EOF {
+					break
+				} else if err != nil {
+					t.Fatalf("Recv: %v", err)
+				}
+				if !bytes.Equal(resp.GetPayload().GetBody(), payload.Body) {
+					t.Fatal("Recv: payload changed in transit")
+				}
+				n++
+			}
+			if n != 3 {
+				t.Errorf("received %d responses, want 3", n)
+			}
+
+			if _, err := client.UnaryCall(context.Background(), &testgrpc.SimpleRequest{Payload: payload}); err != nil {
+				t.Fatalf("UnaryCall: %v", err)
+			}
+			if got := enc.get("/grpc.testing.TestService/StreamingOutputCall"); got != name {
+				t.Errorf("StreamingOutputCall request encoding = %q, want %q", got, name)
+			}
+			if got := enc.get("/grpc.testing.TestService/UnaryCall"); got != "" {
+				t.Errorf("UnaryCall request encoding = %q, want none", got)
+			}
+		})
+	}
+}
+
+// compactBlock returns the encoding of a CompactBlock with n note payloads
+// of realistic sizes, built from random keys and ciphertexts.
+func compactBlock(n int) []byte {
+	random := func(size int) []byte {
+		b := make([]byte, size)
+		rand.Read(b)
+		return b
+	}
+	message := func(fields ...[]byte) []byte { return bytes.Join(fields, nil) }
+	bytesField := func(num protowire.Number, v []byte) []byte {
+		return protowire.AppendBytes(protowire.AppendTag(nil, num, protowire.BytesType), v)
+	}
+	var block []byte
+	block = protowire.AppendVarint(protowire.AppendTag(block, 1, protowire.VarintType), 4_000_000)
+	for range n {
+		note := message(
+			bytesField(1, bytesField(1, random(32))),  // note_commitment
+			bytesField(2, random(32)),                 // ephemeral_key
+			bytesField(3, bytesField(1, random(176))), // encrypted_note
+		)
+		payload := message(
+			bytesField(1, bytesField(1, bytesField(1, nil))), // source: transaction, with the ID stripped
+			bytesField(3, bytesField(2, note)),               // note
+		)
+		block = append(block, bytesField(2, payload)...)
+	}
+	return block
+}
+
+// BenchmarkCompactBlock reports the compressed size of a compact block as a
+// percentage of its encoded size.
+func BenchmarkCompactBlock(b *testing.B) {
+	block := compactBlock(100)
+	for _, name := range []string{Gzip, Zstd} {
+		b.Run(name, func(b *testing.B) {
+			c := encoding.GetCompressor(name)
+			var buf bytes.Buffer
+			b.SetBytes(int64(len(block)))
+			b.ReportAllocs()
+			for b.Loop() {
+				buf.Reset()
+				w, err := c.Compress(&buf)
+				if err != nil {
+					b.Fatal(err)
+				}
+				w.Write(block)
+				if err := w.Close(); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(100*float64(buf.Len())/float64(len(block)), "%size")
+		})
+	}
+}
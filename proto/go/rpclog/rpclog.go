@@ -0,0 +1,308 @@
+// Package rpclog logs the custody, view and node RPCs with log/slog.
+//
+// Every completed RPC is logged at Info, or at Warn if it failed, with its
+// service, method, status code and duration. When the logger is enabled at
+// Debug, the request and response messages are logged as well, rendered with
+// pjson after secrets are removed by Redact. Debug logging is therefore safe
+// to turn on in production: spend keys, viewing keys, signatures and memo
+// plaintexts never reach the log.
+//
+//	l := rpclog.New(slog.Default())
+//	s := grpc.NewServer(l.ServerOptions()...)
+package rpclog
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/penumbra-zone/penumbra/proto/go/pjson"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// redactedMessages are the messages that are removed wholesale: key
+// material, signatures and memo plaintexts.
+var redactedMessages = map[protoreflect.FullName]bool{
+	"penumbra.core.keys.v1.SpendKey":                      true,
+	"penumbra.core.keys.v1.FullViewingKey":                true,
+	"penumbra.core.keys.v1.PayloadKey":                    true,
+	"penumbra.crypto.decaf377_frost.v1.SigningShare":      true,
+	"penumbra.crypto.decaf377_frost.v1.SignatureShare":    true,
+	"penumbra.crypto.decaf377_rdsa.v1.SpendAuthSignature": true,
+	"penumbra.crypto.decaf377_rdsa.v1.BindingSignature":   true,
+	"penumbra.custody.threshold.v1.Signature":             true,
+	"penumbra.core.transaction.v1.MemoPlaintext":          true,
+	"penumbra.core.transaction.v1.MemoPlaintextView":      true,
+}
+
+// redactedFields are secret fields of messages that are otherwise logged.
+var redactedFields = map[protoreflect.FullName]bool{
+	"penumbra.core.transaction.v1.MemoPlan.key":        true,
+	"penumbra.custody.v1.PreAuthorization.Ed25519.sig": true,
+}
+
+// Redact returns a copy of m with secrets cleared, and the paths of the
+// fields that were cleared. Unknown fields are dropped, since they may hold
+// secrets added in a newer schema, as are the contents of Any values whose
+// type is not linked into the binary.
+func Redact(m proto.Message) (proto.Message, []string) {
+	m = proto.Clone(m)
+	var paths []string
+	redact(m.ProtoReflect(), "", &paths)
+	return m, paths
+}
+
+func redact(m protoreflect.Message, path string, paths *[]string) {
+	m.SetUnknown(nil)
+	if m.Descriptor().FullName() == "google.protobuf.Any" {
+		redactAny(m, path, paths)
+		return
+	}
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		p := join(path, string(fd.Name()))
+		if redactedFields[fd.FullName()] || (fd.Message() != nil && redactedMessages[fd.Message().FullName()]) {
+			m.Clear(fd)
+			*paths = append(*paths, p)
+			return true
+		}
+		switch {
+		case fd.IsMap():
+			if fd.MapValue().Message() != nil {
+				v.Map().Range(func(k protoreflect.MapKey, v protoreflect.Value) bool {
+					redact(v.Message(), p+"["+k.String()+"]", paths)
+					return true
+				})
+			}
+		case fd.Message() == nil:
+		case fd.IsList():
+			list := v.List()
+			for i := 0; i < list.Len(); i++ {
+				redact(list.Get(i).Message(), p+"["+strconv.Itoa(i)+"]", paths)
+			}
+		default:
+			redact(v.Message(), p, paths)
+		}
+		return true
+	})
+}
+
+func redactAny(m protoreflect.Message, path string, paths *[]string) {
+	a, ok := m.Interface().(*anypb.Any)
+	if !ok {
+		// A dynamic Any: keep the type URL only.
+		m.Clear(m.Descriptor().Fields().ByName("value"))
+		*paths = append(*paths, join(path, "value"))
+		return
+	}
+	inner, err := a.UnmarshalNew()
+	if err != nil || redactedMessages[inner.ProtoReflect().Descriptor().FullName()] {
+		a.Value = nil
+		*paths = append(*paths, join(path, "value"))
+		return
+	}
+	redact(inner.ProtoReflect(), path, paths)
+	// Marshal only fails for messages missing required fields, which the
+	// unmarshal above would have rejected.
+	a.Value, _ = proto.MarshalOptions{Deterministic: true}.Marshal(inner)
+}
+
+func join(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// Logger logs RPCs to a slog.Logger.
+type Logger struct {
+	l *slog.Logger
+}
+
+// New returns a Logger that writes to l.
+func New(l *slog.Logger) *Logger {
+	return &Logger{l: l}
+}
+
+// ServerOptions returns the interceptors that log every RPC served by a
+// grpc.Server.
+func (l *Logger) ServerOptions() []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(l.UnaryServerInterceptor()),
+		grpc.ChainStreamInterceptor(l.StreamServerInterceptor()),
+	}
+}
+
+// DialOptions returns the interceptors that log every RPC made on a client
+// connection.
+func (l *Logger) DialOptions() []grpc.DialOption {
+	return []grpc.DialOption{
+		grpc.WithChainUnaryInterceptor(l.UnaryClientInterceptor()),
+		grpc.WithChainStreamInterceptor(l.StreamClientInterceptor()),
+	}
+}
+
+// UnaryServerInterceptor logs unary RPCs on the server.
+func (l *Logger) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		c := l.start(ctx, "server", info.FullMethod)
+		c.message("request", req)
+		resp, err := handler(ctx, req)
+		if err == nil {
+			c.message("response", resp)
+		}
+		c.done(err)
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor logs streaming RPCs on the server. At Debug, each
+// message sent or received is logged as it passes.
+func (l *Logger) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		c := l.start(ss.Context(), "server", info.FullMethod)
+		err := handler(srv, &serverStream{ServerStream: ss, c: c})
+		c.done(err)
+		return err
+	}
+}
+
+// UnaryClientInterceptor logs unary RPCs on the client.
+func (l *Logger) UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		c := l.start(ctx, "client", method)
+		c.message("request", req)
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		if err == nil {
+			c.message("response", reply)
+		}
+		c.done(err)
+		return err
+	}
+}
+
+// StreamClientInterceptor logs streaming RPCs on the client. The RPC is
+// logged as complete when RecvMsg returns an error, including io.EOF at the
+// normal end of a stream.
+func (l *Logger) StreamClientInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		c := l.start(ctx, "client", method)
+		cs, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			c.done(err)
+			return nil, err
+		}
+		return &clientStream{ClientStream: cs, c: c}, nil
+	}
+}
+
+// call logs a single RPC.
+type call struct {
+	l     *slog.Logger
+	ctx   context.Context
+	start time.Time
+}
+
+func (l *Logger) start(ctx context.Context, kind, fullMethod string) *call {
+	service, method, _ := strings.Cut(strings.TrimPrefix(fullMethod, "/"), "/")
+	return &call{
+		l:     l.l.With("kind", kind, "service", service, "method", method),
+		ctx:   ctx,
+		start: time.Now(),
+	}
+}
+
+// message logs a request, response or stream message at Debug.
+func (c *call) message(msg string, v any) {
+	if !c.l.Enabled(c.ctx, slog.LevelDebug) {
+		return
+	}
+	m, ok := v.(proto.Message)
+	if !ok {
+		return
+	}
+	m, paths := Redact(m)
+	attrs := []slog.Attr{}
+	if b, err := pjson.Marshal(m); err != nil {
+		attrs = append(attrs, slog.String("payload_error", err.Error()))
+	} else {
+		attrs = append(attrs, slog.Any("payload", json.RawMessage(b)))
+	}
+	if len(paths) > 0 {
+		attrs = append(attrs, slog.Any("redacted", paths))
+	}
+	c.l.LogAttrs(c.ctx, slog.LevelDebug, "rpc "+msg, attrs...)
+}
+
+func (c *call) done(err error) {
+	code := status.Code(err)
+	attrs := []slog.Attr{
+		slog.String("code", code.String()),
+		slog.Duration("duration", time.Since(c.start)),
+	}
+	level := slog.LevelInfo
+	if code != codes.OK {
+		level = slog.LevelWarn
+		attrs = append(attrs, slog.String("error", status.Convert(err).Message()))
+	}
+	c.l.LogAttrs(c.ctx, level, "rpc finished", attrs...)
+}
+
+type serverStream struct {
+	grpc.ServerStream
+	c *call
+}
+
+func (s *serverStream) SendMsg(m any) error {
+	err := s.ServerStream.SendMsg(m)
+	if err == nil {
+		s.c.message("sent", m)
+	}
+	return err
+}
+
+func (s *serverStream) RecvMsg(m any) error {
+	err := s.ServerStream.RecvMsg(m)
+	if err == nil {
+		s.c.message("received", m)
+	}
+	return err
+}
+
+type clientStream struct {
+	grpc.ClientStream
+	c    *call
+	done bool
+}
+
+func (s *clientStream) SendMsg(m any) error {
+	err := s.ClientStream.SendMsg(m)
+	if err == nil {
+		s.c.message("sent", m)
+	}
+	return err
+}
+
+func (s *clientStream) RecvMsg(m any) error {
+	err := s.ClientStream.RecvMsg(m)
+	switch {
+	case err == nil:
+		s.c.message("received", m)
+	case !s.done:
+		s.done = true
+		if err == io.EOF {
+			s.c.done(nil)
+		} else {
+			s.c.done(err)
+		}
+	}
+	return err
+}
@@ -0,0 +1,162 @@
+package rpclog
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"log/slog"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/penumbra-zone/penumbra/proto/go/inprocess"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// Minimal copies of the secret-bearing messages, and a request that holds
+// them in each position Redact handles.
+var testFiles = []string{`
+name: "penumbra/core/keys/v1/keys.proto"
+package: "penumbra.core.keys.v1"
+syntax: "proto3"
+message_type: {
+  name: "SpendKey"
+  field: {name: "inner" number: 1 type: TYPE_BYTES label: LABEL_OPTIONAL json_name: "inner"}
+}`, `
+name: "penumbra/core/transaction/v1/transaction.proto"
+package: "penumbra.core.transaction.v1"
+syntax: "proto3"
+message_type: {
+  name: "MemoPlaintext"
+  field: {name: "text" number: 2 type: TYPE_STRING label: LABEL_OPTIONAL json_name: "text"}
+}
+message_type: {
+  name: "MemoPlan"
+  field: {name: "plaintext" number: 1 type: TYPE_MESSAGE type_name: ".penumbra.core.transaction.v1.MemoPlaintext" label: LABEL_OPTIONAL json_name: "plaintext"}
+  field: {name: "key" number: 2 type: TYPE_BYTES label: LABEL_OPTIONAL json_name: "key"}
+}`, `
+name: "rpclogtest/v1/test.proto"
+package: "rpclogtest.v1"
+syntax: "proto3"
+dependency: ["penumbra/core/keys/v1/keys.proto", "penumbra/core/transaction/v1/transaction.proto"]
+message_type: {
+  name: "Request"
+  field: {name: "spend_key" number: 1 type: TYPE_MESSAGE type_name: ".penumbra.core.keys.v1.SpendKey" label: LABEL_OPTIONAL json_name: "spendKey"}
+  field: {name: "memos" number: 2 type: TYPE_MESSAGE type_name: ".penumbra.core.transaction.v1.MemoPlan" label: LABEL_REPEATED json_name: "memos"}
+  field: {name: "by_name" number: 3 type: TYPE_MESSAGE type_name: ".rpclogtest.v1.Request.ByNameEntry" label: LABEL_REPEATED json_name: "byName"}
+  field: {name: "label" number: 4 type: TYPE_STRING label: LABEL_OPTIONAL json_name: "label"}
+  nested_type: {
+    name: "ByNameEntry"
+    field: {name: "key" number: 1 type: TYPE_STRING label: LABEL_OPTIONAL json_name: "key"}
+    field: {name: "value" number: 2 type: TYPE_MESSAGE type_name: ".penumbra.core.transaction.v1.MemoPlan" label: LABEL_OPTIONAL json_name: "value"}
+    options: {map_entry: true}
+  }
+}`}
+
+func testRequest(t *testing.T, s string) *dynamicpb.Message {
+	t.Helper()
+	files := new(protoregistry.Files)
+	for _, text := range testFiles {
+		fdp := new(descriptorpb.FileDescriptorProto)
+		if err := prototext.Unmarshal([]byte(text), fdp); err != nil {
+			t.Fatal(err)
+		}
+		fd, err := protodesc.NewFile(fdp, files)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := files.RegisterFile(fd); err != nil {
+			t.Fatal(err)
+		}
+	}
+	d, err := files.FindDescriptorByName("rpclogtest.v1.Request")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := dynamicpb.NewMessage(d.(protoreflect.MessageDescriptor))
+	if err := protojson.Unmarshal([]byte(s), m); err != nil {
+		t.Fatalf("protojson.Unmarshal(%s): %v", s, err)
+	}
+	return m
+}
+
+func TestRedact(t *testing.T) {
+	secret := base64.StdEncoding.EncodeToString([]byte("secret"))
+	in := testRequest(t, `{
+		"spendKey": {"inner": "`+secret+`"},
+		"memos": [{"plaintext": {"text": "hello"}, "key": "`+secret+`"}],
+		"byName": {"a": {"key": "`+secret+`"}},
+		"label": "kept"
+	}`)
+	in.SetUnknown(protoreflect.RawFields{0x2a, 0x01, 0x00})
+
+	orig := proto.Clone(in)
+	out, paths := Redact(in)
+	want := []string{"spend_key", "memos[0].plaintext", "memos[0].key", "by_name[a].key"}
+	slices.Sort(paths)
+	slices.Sort(want)
+	if !slices.Equal(paths, want) {
+		t.Errorf("Redact paths = %q, want %q", paths, want)
+	}
+	b, err := protojson.Marshal(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(b), secret) || strings.Contains(string(b), "hello") || !strings.Contains(string(b), "kept") {
+		t.Errorf("Redact = %s", b)
+	}
+	if len(out.ProtoReflect().GetUnknown()) != 0 {
+		t.Error("Redact kept unknown fields")
+	}
+	if !proto.Equal(in, orig) {
+		t.Error("Redact modified its input")
+	}
+}
+
+func TestLogger(t *testing.T) {
+	for _, tt := range []struct {
+		level       slog.Level
+		wantPayload bool
+	}{
+		{slog.LevelInfo, false},
+		{slog.LevelDebug, true},
+	} {
+		var buf bytes.Buffer
+		l := New(slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: tt.level})))
+
+		srv := health.NewServer()
+		conn, err := inprocess.New(func(s grpc.ServiceRegistrar) {
+			healthpb.RegisterHealthServer(s, srv)
+		}, l.ServerOptions(), l.DialOptions()...)
+		if err != nil {
+			t.Fatalf("inprocess.New: %v", err)
+		}
+		client := healthpb.NewHealthClient(conn)
+		client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: "penumbra.view.v1.ViewService"})
+		conn.Close()
+
+		out := buf.String()
+		for _, want := range []string{
+			`"level":"WARN","msg":"rpc finished","kind":"server","service":"grpc.health.v1.Health","method":"Check","code":"NotFound"`,
+			`"level":"WARN","msg":"rpc finished","kind":"client","service":"grpc.health.v1.Health","method":"Check","code":"NotFound"`,
+		} {
+			if !strings.Contains(out, want) {
+				t.Errorf("level %v: log does not contain %s:\n%s", tt.level, want, out)
+			}
+		}
+		payload := `"msg":"rpc request","kind":"client","service":"grpc.health.v1.Health","method":"Check","payload":{"service":"penumbra.view.v1.ViewService"}`
+		if got := strings.Contains(out, payload); got != tt.wantPayload {
+			t.Errorf("level %v: log contains payload = %v, want %v:\n%s", tt.level, got, tt.wantPayload, out)
+		}
+	}
+}
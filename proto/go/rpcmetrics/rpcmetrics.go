@@ -0,0 +1,284 @@
+// Package rpcmetrics records Prometheus metrics for the custody, view and
+// node RPCs.
+//
+// The metric names follow go-grpc-prometheus, so existing gRPC dashboards
+// work unchanged. Labels are limited to the service, the method, the RPC
+// type and the status code: all of them are bounded by the registered
+// services, so the series count stays fixed no matter how many clients,
+// accounts or addresses a server sees.
+//
+//	m := rpcmetrics.NewServerMetrics()
+//	prometheus.MustRegister(m)
+//	s := grpc.NewServer(m.ServerOptions()...)
+package rpcmetrics
+
+import (
+	"context"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// DefaultBuckets are the latency histogram buckets, in seconds. They reach
+// further than the usual web defaults because streaming RPCs, such as the
+// compact block stream during sync, stay open for minutes.
+var DefaultBuckets = []float64{0.005, 0.025, 0.1, 0.25, 1, 2.5, 10, 30, 60, 300, 900}
+
+const (
+	unary        = "unary"
+	clientStream = "client_stream"
+	serverStream = "server_stream"
+	bidiStream   = "bidi_stream"
+)
+
+func streamType(clientStreams, serverStreams bool) string {
+	switch {
+	case clientStreams && serverStreams:
+		return bidiStream
+	case clientStreams:
+		return clientStream
+	case serverStreams:
+		return serverStream
+	default:
+		return unary
+	}
+}
+
+// splitMethod splits "/package.Service/Method" into its service and method.
+func splitMethod(fullMethod string) (string, string) {
+	service, method, ok := strings.Cut(strings.TrimPrefix(fullMethod, "/"), "/")
+	if !ok {
+		return "unknown", "unknown"
+	}
+	return service, method
+}
+
+// metrics holds the collectors shared by the client and server sides, which
+// differ only in their metric name prefix.
+type metrics struct {
+	started  *prometheus.CounterVec
+	handled  *prometheus.CounterVec
+	latency  *prometheus.HistogramVec
+	received *prometheus.CounterVec
+	sent     *prometheus.CounterVec
+}
+
+func newMetrics(prefix, verb string, buckets []float64) metrics {
+	labels := []string{"grpc_type", "grpc_service", "grpc_method"}
+	return metrics{
+		started: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: prefix + "_started_total",
+			Help: "Total number of RPCs " + verb + ".",
+		}, labels),
+		handled: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: prefix + "_handled_total",
+			Help: "Total number of RPCs completed, regardless of success or failure.",
+		}, append(labels, "grpc_code")),
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    prefix + "_handling_seconds",
+			Help:    "Time from the start of an RPC until it completed.",
+			Buckets: buckets,
+		}, labels),
+		received: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: prefix + "_msg_received_total",
+			Help: "Total number of stream messages received.",
+		}, labels),
+		sent: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: prefix + "_msg_sent_total",
+			Help: "Total number of stream messages sent.",
+		}, labels),
+	}
+}
+
+func (m metrics) describe(ch chan<- *prometheus.Desc) {
+	m.started.Describe(ch)
+	m.handled.Describe(ch)
+	m.latency.Describe(ch)
+	m.received.Describe(ch)
+	m.sent.Describe(ch)
+}
+
+func (m metrics) collect(ch chan<- prometheus.Metric) {
+	m.started.Collect(ch)
+	m.handled.Collect(ch)
+	m.latency.Collect(ch)
+	m.received.Collect(ch)
+	m.sent.Collect(ch)
+}
+
+// call tracks a single RPC.
+type call struct {
+	m      metrics
+	labels []string
+	start  time.Time
+}
+
+func (m metrics) start(typ, fullMethod string) call {
+	service, method := splitMethod(fullMethod)
+	labels := []string{typ, service, method}
+	m.started.WithLabelValues(labels...).Inc()
+	return call{m: m, labels: labels, start: time.Now()}
+}
+
+func (c call) done(err error) {
+	c.m.handled.WithLabelValues(append(c.labels, status.Code(err).String())...).Inc()
+	c.m.latency.WithLabelValues(c.labels...).Observe(time.Since(c.start).Seconds())
+}
+
+// ServerMetrics records metrics for the RPCs served by a grpc.Server. It is
+// a prometheus.Collector and must be registered to be exported.
+type ServerMetrics struct {
+	m metrics
+}
+
+// NewServerMetrics returns ServerMetrics with the given latency buckets, or
+// DefaultBuckets if none are given.
+func NewServerMetrics(buckets ...float64) *ServerMetrics {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	return &ServerMetrics{m: newMetrics("grpc_server", "started on the server", buckets)}
+}
+
+// Describe implements prometheus.Collector.
+func (s *ServerMetrics) Describe(ch chan<- *prometheus.Desc) { s.m.describe(ch) }
+
+// Collect implements prometheus.Collector.
+func (s *ServerMetrics) Collect(ch chan<- prometheus.Metric) { s.m.collect(ch) }
+
+// ServerOptions returns the interceptors that record the metrics.
+func (s *ServerMetrics) ServerOptions() []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(s.UnaryServerInterceptor()),
+		grpc.ChainStreamInterceptor(s.StreamServerInterceptor()),
+	}
+}
+
+// UnaryServerInterceptor records metrics for unary RPCs.
+func (s *ServerMetrics) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		c := s.m.start(unary, info.FullMethod)
+		resp, err := handler(ctx, req)
+		c.done(err)
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor records metrics for streaming RPCs, including a
+// count of the messages sent and received.
+func (s *ServerMetrics) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		c := s.m.start(streamType(info.IsClientStream, info.IsServerStream), info.FullMethod)
+		err := handler(srv, &countingServerStream{ServerStream: ss, c: c})
+		c.done(err)
+		return err
+	}
+}
+
+type countingServerStream struct {
+	grpc.ServerStream
+	c call
+}
+
+func (s *countingServerStream) SendMsg(m any) error {
+	err := s.ServerStream.SendMsg(m)
+	if err == nil {
+		s.c.m.sent.WithLabelValues(s.c.labels...).Inc()
+	}
+	return err
+}
+
+func (s *countingServerStream) RecvMsg(m any) error {
+	err := s.ServerStream.RecvMsg(m)
+	if err == nil {
+		s.c.m.received.WithLabelValues(s.c.labels...).Inc()
+	}
+	return err
+}
+
+// ClientMetrics records metrics for the RPCs made on a client connection. It
+// is a prometheus.Collector and must be registered to be exported.
+type ClientMetrics struct {
+	m metrics
+}
+
+// NewClientMetrics returns ClientMetrics with the given latency buckets, or
+// DefaultBuckets if none are given.
+func NewClientMetrics(buckets ...float64) *ClientMetrics {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	return &ClientMetrics{m: newMetrics("grpc_client", "started on the client", buckets)}
+}
+
+// Describe implements prometheus.Collector.
+func (c *ClientMetrics) Describe(ch chan<- *prometheus.Desc) { c.m.describe(ch) }
+
+// Collect implements prometheus.Collector.
+func (c *ClientMetrics) Collect(ch chan<- prometheus.Metric) { c.m.collect(ch) }
+
+// DialOptions returns the interceptors that record the metrics.
+func (c *ClientMetrics) DialOptions() []grpc.DialOption {
+	return []grpc.DialOption{
+		grpc.WithChainUnaryInterceptor(c.UnaryClientInterceptor()),
+		grpc.WithChainStreamInterceptor(c.StreamClientInterceptor()),
+	}
+}
+
+// UnaryClientInterceptor records metrics for unary RPCs.
+func (c *ClientMetrics) UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		call := c.m.start(unary, method)
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		call.done(err)
+		return err
+	}
+}
+
+// StreamClientInterceptor records metrics for streaming RPCs. The RPC
+// completes when the stream returns an error from RecvMsg, including io.EOF
+// at the normal end of a stream.
+func (c *ClientMetrics) StreamClientInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		call := c.m.start(streamType(desc.ClientStreams, desc.ServerStreams), method)
+		cs, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			call.done(err)
+			return nil, err
+		}
+		return &countingClientStream{ClientStream: cs, c: call}, nil
+	}
+}
+
+type countingClientStream struct {
+	grpc.ClientStream
+	c    call
+	done bool
+}
+
+func (s *countingClientStream) SendMsg(m any) error {
+	err := s.ClientStream.SendMsg(m)
+	if err == nil {
+		s.c.m.sent.WithLabelValues(s.c.labels...).Inc()
+	}
+	return err
+}
+
+func (s *countingClientStream) RecvMsg(m any) error {
+	err := s.ClientStream.RecvMsg(m)
+	switch {
+	case err == nil:
+		s.c.m.received.WithLabelValues(s.c.labels...).Inc()
+	case !s.done:
+		s.done = true
+		if err == io.EOF {
+			err = nil
+		}
+		s.c.done(err)
+	}
+	return err
+}
@@ -0,0 +1,131 @@
+package rpcmetrics
+
+import (
+	"context"
+	"testing"
+
+	"github.com/penumbra-zone/penumbra/proto/go/inprocess"
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// value returns the value of the counter or histogram sample count with the
+// given name and labels, or -1 if there is none.
+func value(t *testing.T, reg *prometheus.Registry, name string, labels map[string]string) float64 {
+	t.Helper()
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("Gather: %v", err)
+	}
+	for _, f := range families {
+		if f.GetName() != name {
+			continue
+		}
+	metrics:
+		for _, m := range f.GetMetric() {
+			for _, l := range m.GetLabel() {
+				if want, ok := labels[l.GetName()]; ok && want != l.GetValue() {
+					continue metrics
+				}
+			}
+			if h := m.GetHistogram(); h != nil {
+				return float64(h.GetSampleCount())
+			}
+			return m.GetCounter().GetValue()
+		}
+	}
+	return -1
+}
+
+func TestMetrics(t *testing.T) {
+	sm, cm := NewServerMetrics(), NewClientMetrics()
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(sm, cm)
+
+	srv := health.NewServer()
+	srv.SetServingStatus("penumbra.view.v1.ViewService", healthpb.HealthCheckResponse_SERVING)
+	conn, err := inprocess.New(func(s grpc.ServiceRegistrar) {
+		healthpb.RegisterHealthServer(s, srv)
+	}, sm.ServerOptions(), cm.DialOptions()...)
+	if err != nil {
+		t.Fatalf("inprocess.New: %v", err)
+	}
+	defer conn.Close()
+	client := healthpb.NewHealthClient(conn)
+
+	ctx := context.Background()
+	if _, err := client.Check(ctx, &healthpb.HealthCheckRequest{Service: "penumbra.view.v1.ViewService"}); err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	if _, err := client.Check(ctx, &healthpb.HealthCheckRequest{Service: "unknown"}); err == nil {
+		t.Fatal("Check(unknown): want error")
+	}
+	list, err := client.List(ctx, &healthpb.HealthListRequest{})
+	if err != nil || len(list.GetStatuses()) == 0 {
+		t.Fatalf("List = %v, %v", list, err)
+	}
+
+	check := map[string]string{"grpc_service": "grpc.health.v1.Health", "grpc_method": "Check", "grpc_type": "unary"}
+	for _, prefix := range []string{"grpc_server", "grpc_client"} {
+		if got := value(t, reg, prefix+"_started_total", check); got != 2 {
+			t.Errorf("%s_started_total = %v, want 2", prefix, got)
+		}
+		for code, want := range map[string]float64{"OK": 1, "NotFound": 1} {
+			labels := map[string]string{"grpc_method": "Check", "grpc_code": code}
+			if got := value(t, reg, prefix+"_handled_total", labels); got != want {
+				t.Errorf("%s_handled_total{grpc_code=%q} = %v, want %v", prefix, code, got, want)
+			}
+		}
+		if got := value(t, reg, prefix+"_handling_seconds", check); got != 2 {
+			t.Errorf("%s_handling_seconds count = %v, want 2", prefix, got)
+		}
+	}
+}
+
+func TestStreamMetrics(t *testing.T) {
+	sm, cm := NewServerMetrics(), NewClientMetrics()
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(sm, cm)
+
+	srv := health.NewServer()
+	conn, err := inprocess.New(func(s grpc.ServiceRegistrar) {
+		healthpb.RegisterHealthServer(s, srv)
+	}, sm.ServerOptions(), cm.DialOptions()...)
+	if err != nil {
+		t.Fatalf("inprocess.New: %v", err)
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream, err := healthpb.NewHealthClient(conn).Watch(ctx, &healthpb.HealthCheckRequest{Service: "custody"})
+	if err != nil {
+		t.Fatalf("Watch: %v", err)
+	}
+	if _, err := stream.Recv(); err != nil {
+		t.Fatalf("Recv: %v", err)
+	}
+	srv.SetServingStatus("custody", healthpb.HealthCheckResponse_SERVING)
+	if _, err := stream.Recv(); err != nil {
+		t.Fatalf("Recv: %v", err)
+	}
+
+	watch := map[string]string{"grpc_method": "Watch", "grpc_type": "server_stream"}
+	if got := value(t, reg, "grpc_client_msg_received_total", watch); got != 2 {
+		t.Errorf("grpc_client_msg_received_total = %v, want 2", got)
+	}
+	if got := value(t, reg, "grpc_client_handled_total", watch); got != -1 {
+		t.Errorf("grpc_client_handled_total = %v before the stream ended, want none", got)
+	}
+
+	cancel()
+	if _, err := stream.Recv(); err == nil {
+		t.Fatal("Recv after cancel: want error")
+	}
+	canceled := map[string]string{"grpc_method": "Watch", "grpc_code": "Canceled"}
+	if got := value(t, reg, "grpc_client_handled_total", canceled); got != 1 {
+		t.Errorf("grpc_client_handled_total{grpc_code=Canceled} = %v, want 1", got)
+	}
+}
@@ -0,0 +1,434 @@
+// Package rpcreplay records the RPCs a client makes to fixture files and
+// replays them, so integration tests of code that calls a custodian, a view
+// server or a node can run without one.
+//
+// A test records once against live services, then replays from then on:
+//
+//	var record = flag.Bool("record", false, "record RPC fixtures")
+//
+//	func TestSend(t *testing.T) {
+//		var conn grpc.ClientConnInterface
+//		if *record {
+//			rec := rpcreplay.NewRecorder()
+//			cc, err := grpc.NewClient(target, append(opts, rec.DialOptions()...)...)
+//			...
+//			defer rec.Save("testdata/send.json")
+//			conn = cc
+//		} else {
+//			p, err := rpcreplay.Load("testdata/send.json")
+//			...
+//			conn = p
+//		}
+//		client := viewv1.NewViewServiceClient(conn)
+//
+// Fixtures are JSON, with messages in the canonical protobuf JSON mapping so
+// that they can be read and edited in review. They hold the messages
+// exactly as sent, signatures and key material included, so record against
+// test keys only.
+//
+// A replayed call is matched to the first unused recording with the same
+// method and an equal request; a stream is matched on its first request.
+// Metadata, headers and trailers are not recorded.
+package rpcreplay
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sync"
+
+	"github.com/penumbra-zone/penumbra/proto/go/descriptors"
+	spb "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+// Interaction is one recorded call.
+type Interaction struct {
+	// Method is the full gRPC method name, "/package.Service/Method".
+	Method string `json:"method"`
+	// Request is the request, or for a stream the first message sent.
+	Request json.RawMessage `json:"request,omitempty"`
+	// Responses are the messages received, in order.
+	Responses []json.RawMessage `json:"responses,omitempty"`
+	// Status is the google.rpc.Status the call ended with, or nil if it
+	// succeeded.
+	Status json.RawMessage `json:"status,omitempty"`
+}
+
+// Cassette is the content of a fixture file.
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+var (
+	marshalOptions   = protojson.MarshalOptions{Resolver: resolver{}}
+	unmarshalOptions = protojson.UnmarshalOptions{Resolver: resolver{}}
+)
+
+// resolver finds the types in Any values among the Go types linked into the
+// binary, such as the google.rpc error details in a status, and then among
+// the Penumbra descriptors.
+type resolver struct{}
+
+func (resolver) FindMessageByName(name protoreflect.FullName) (protoreflect.MessageType, error) {
+	if mt, err := protoregistry.GlobalTypes.FindMessageByName(name); err == nil {
+		return mt, nil
+	}
+	return descriptors.Types().FindMessageByName(name)
+}
+
+func (resolver) FindMessageByURL(url string) (protoreflect.MessageType, error) {
+	if mt, err := protoregistry.GlobalTypes.FindMessageByURL(url); err == nil {
+		return mt, nil
+	}
+	return descriptors.Types().FindMessageByURL(url)
+}
+
+func (resolver) FindExtensionByName(name protoreflect.FullName) (protoreflect.ExtensionType, error) {
+	if xt, err := protoregistry.GlobalTypes.FindExtensionByName(name); err == nil {
+		return xt, nil
+	}
+	return descriptors.Types().FindExtensionByName(name)
+}
+
+func (resolver) FindExtensionByNumber(message protoreflect.FullName, field protoreflect.FieldNumber) (protoreflect.ExtensionType, error) {
+	if xt, err := protoregistry.GlobalTypes.FindExtensionByNumber(message, field); err == nil {
+		return xt, nil
+	}
+	return descriptors.Types().FindExtensionByNumber(message, field)
+}
+
+func marshal(m any) (json.RawMessage, error) {
+	pm, ok := m.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("rpcreplay: %T is not a protobuf message", m)
+	}
+	return marshalOptions.Marshal(pm)
+}
+
+func unmarshal(b json.RawMessage, m any) error {
+	pm, ok := m.(proto.Message)
+	if !ok {
+		return fmt.Errorf("rpcreplay: %T is not a protobuf message", m)
+	}
+	return unmarshalOptions.Unmarshal(b, pm)
+}
+
+// Load reads a fixture file written by Recorder.Save, and returns a Player
+// for it.
+func Load(path string) (*Player, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var c Cassette
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, fmt.Errorf("rpcreplay: %s: %w", path, err)
+	}
+	return NewPlayer(&c), nil
+}
+
+// Recorder records the calls made on client connections it is applied to.
+// It is safe for concurrent use.
+type Recorder struct {
+	mu       sync.Mutex
+	cassette Cassette
+	err      error
+}
+
+// NewRecorder returns an empty Recorder.
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+// DialOptions returns the interceptors that record calls.
+func (r *Recorder) DialOptions() []grpc.DialOption {
+	return []grpc.DialOption{
+		grpc.WithChainUnaryInterceptor(r.unary),
+		grpc.WithChainStreamInterceptor(r.stream),
+	}
+}
+
+// Cassette returns the calls recorded so far, in the order they finished.
+func (r *Recorder) Cassette() *Cassette {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return &Cassette{Interactions: slices.Clone(r.cassette.Interactions)}
+}
+
+// Save writes the recorded calls to path. It fails if any message could not
+// be recorded.
+func (r *Recorder) Save(path string) error {
+	r.mu.Lock()
+	err := r.err
+	r.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(r.Cassette(), "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(b, '\n'), 0o644)
+}
+
+func (r *Recorder) add(in Interaction, errs ...error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := errors.Join(errs...); err != nil && r.err == nil {
+		r.err = fmt.Errorf("rpcreplay: recording %s: %w", in.Method, err)
+	}
+	r.cassette.Interactions = append(r.cassette.Interactions, in)
+}
+
+// recordStatus returns the status to record for err, which is nil for
+// success and for io.EOF ending a stream.
+func recordStatus(err error) (json.RawMessage, error) {
+	if err == nil || err == io.EOF {
+		return nil, nil
+	}
+	return marshal(status.Convert(err).Proto())
+}
+
+func (r *Recorder) unary(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	err := invoker(ctx, method, req, reply, cc, opts...)
+	in := Interaction{Method: method}
+	var reqErr, respErr, stErr error
+	in.Request, reqErr = marshal(req)
+	if err == nil {
+		var resp json.RawMessage
+		resp, respErr = marshal(reply)
+		in.Responses = []json.RawMessage{resp}
+	}
+	in.Status, stErr = recordStatus(err)
+	r.add(in, reqErr, respErr, stErr)
+	return err
+}
+
+func (r *Recorder) stream(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	s, err := streamer(ctx, desc, cc, method, opts...)
+	if err != nil {
+		st, stErr := recordStatus(err)
+		r.add(Interaction{Method: method, Status: st}, stErr)
+		return nil, err
+	}
+	return &recordingStream{ClientStream: s, recorder: r, in: Interaction{Method: method}}, nil
+}
+
+// recordingStream records a stream, adding it to the Recorder when RecvMsg
+// returns an error or io.EOF. Streams abandoned before then are not
+// recorded.
+type recordingStream struct {
+	grpc.ClientStream
+	recorder *Recorder
+
+	mu   sync.Mutex
+	in   Interaction
+	errs []error
+	sent bool
+	done bool
+}
+
+func (s *recordingStream) SendMsg(m any) error {
+	s.mu.Lock()
+	if !s.sent {
+		s.sent = true
+		var err error
+		s.in.Request, err = marshal(m)
+		s.errs = append(s.errs, err)
+	}
+	s.mu.Unlock()
+	return s.ClientStream.SendMsg(m)
+}
+
+func (s *recordingStream) RecvMsg(m any) error {
+	err := s.ClientStream.RecvMsg(m)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.done {
+		return err
+	}
+	if err == nil {
+		resp, merr := marshal(m)
+		s.in.Responses = append(s.in.Responses, resp)
+		s.errs = append(s.errs, merr)
+		return nil
+	}
+	s.done = true
+	st, stErr := recordStatus(err)
+	s.in.Status = st
+	s.recorder.add(s.in, append(s.errs, stErr)...)
+	return err
+}
+
+// Player replays a Cassette. It implements grpc.ClientConnInterface, so it
+// can be passed to any generated New<Service>Client function. It is safe
+// for concurrent use.
+type Player struct {
+	mu           sync.Mutex
+	interactions []Interaction
+	used         []bool
+}
+
+// NewPlayer returns a Player for the calls in c.
+func NewPlayer(c *Cassette) *Player {
+	return &Player{interactions: c.Interactions, used: make([]bool, len(c.Interactions))}
+}
+
+// Unused returns the recorded calls that have not been replayed, so a test
+// can check that the code under test made all of them.
+func (p *Player) Unused() []Interaction {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var unused []Interaction
+	for i, in := range p.interactions {
+		if !p.used[i] {
+			unused = append(unused, in)
+		}
+	}
+	return unused
+}
+
+// match finds and consumes the first unused recording of method whose
+// request equals req. A call for which there is none fails with NotFound.
+func (p *Player) match(method string, req any) (Interaction, error) {
+	pm, ok := req.(proto.Message)
+	if !ok {
+		return Interaction{}, fmt.Errorf("rpcreplay: %T is not a protobuf message", req)
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for i, in := range p.interactions {
+		if p.used[i] || in.Method != method {
+			continue
+		}
+		recorded := pm.ProtoReflect().New().Interface()
+		if len(in.Request) > 0 {
+			if err := unmarshalOptions.Unmarshal(in.Request, recorded); err != nil {
+				return Interaction{}, fmt.Errorf("rpcreplay: recorded request for %s: %w", method, err)
+			}
+		}
+		if proto.Equal(recorded, pm) {
+			p.used[i] = true
+			return in, nil
+		}
+	}
+	b, _ := marshal(req)
+	return Interaction{}, status.Errorf(codes.NotFound, "rpcreplay: no recorded call to %s with request %s", method, b)
+}
+
+// replayStatus returns the error for a recorded status.
+func replayStatus(in Interaction) error {
+	if len(in.Status) == 0 {
+		return nil
+	}
+	st := new(spb.Status)
+	if err := unmarshalOptions.Unmarshal(in.Status, st); err != nil {
+		return fmt.Errorf("rpcreplay: recorded status for %s: %w", in.Method, err)
+	}
+	return status.ErrorProto(st)
+}
+
+// Invoke replays a unary call.
+func (p *Player) Invoke(ctx context.Context, method string, args, reply any, _ ...grpc.CallOption) error {
+	if err := ctx.Err(); err != nil {
+		return status.FromContextError(err).Err()
+	}
+	in, err := p.match(method, args)
+	if err != nil {
+		return err
+	}
+	if err := replayStatus(in); err != nil {
+		return err
+	}
+	if len(in.Responses) != 1 {
+		return fmt.Errorf("rpcreplay: recorded %d responses for unary call to %s", len(in.Responses), method)
+	}
+	return unmarshal(in.Responses[0], reply)
+}
+
+// NewStream replays a stream. The recording is matched when the first
+// message is sent, or when RecvMsg is first called if none was.
+func (p *Player) NewStream(ctx context.Context, _ *grpc.StreamDesc, method string, _ ...grpc.CallOption) (grpc.ClientStream, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, status.FromContextError(err).Err()
+	}
+	return &replayStream{ctx: ctx, player: p, method: method}, nil
+}
+
+type replayStream struct {
+	ctx    context.Context
+	player *Player
+	method string
+
+	mu      sync.Mutex
+	matched bool
+	in      Interaction
+	err     error
+	next    int
+}
+
+func (s *replayStream) Header() (metadata.MD, error) { return metadata.MD{}, nil }
+func (s *replayStream) Trailer() metadata.MD         { return metadata.MD{} }
+func (s *replayStream) CloseSend() error             { return nil }
+func (s *replayStream) Context() context.Context     { return s.ctx }
+
+func (s *replayStream) SendMsg(m any) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.matched {
+		s.matched = true
+		s.in, s.err = s.player.match(s.method, m)
+	}
+	return nil
+}
+
+func (s *replayStream) RecvMsg(m any) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.matched {
+		s.matched = true
+		// A stream with no request is matched on the first recording of
+		// its method that also has none.
+		s.in, s.err = s.player.matchEmpty(s.method)
+	}
+	if s.err != nil {
+		return s.err
+	}
+	if err := s.ctx.Err(); err != nil {
+		return status.FromContextError(err).Err()
+	}
+	if s.next < len(s.in.Responses) {
+		s.next++
+		return unmarshal(s.in.Responses[s.next-1], m)
+	}
+	if err := replayStatus(s.in); err != nil {
+		return err
+	}
+	return io.EOF
+}
+
+// matchEmpty finds and consumes the first unused recording of method with
+// no request.
+func (p *Player) matchEmpty(method string) (Interaction, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for i, in := range p.interactions {
+		if !p.used[i] && in.Method == method && len(in.Request) == 0 {
+			p.used[i] = true
+			return in, nil
+		}
+	}
+	return Interaction{}, status.Errorf(codes.NotFound, "rpcreplay: no recorded call to %s without a request", method)
+}
@@ -0,0 +1,133 @@
+// Package rpcretry provides a gRPC service config with retry policies for
+// the Penumbra services, so that clients get consistent behaviour without
+// each choosing its own.
+//
+//	conn, err := grpc.NewClient(target, rpcretry.DialOptions()...)
+//
+// localrpc.Dial applies them itself.
+//
+// Only calls that are safe to repeat are retried: node queries, and view
+// service methods that read state or build without signing. Custody
+// methods, which may prompt a user for approval, and methods that broadcast
+// transactions are never retried. Retries are limited to UNAVAILABLE, which
+// grpc reports when the request never reached the server or the connection
+// failed, and for node queries RESOURCE_EXHAUSTED, which public RPC
+// endpoints return when rate limiting.
+//
+// A server can override these defaults by publishing its own service config
+// through the name resolver.
+//
+// No hedging policies are included: grpc-go parses them but does not
+// implement hedging, so they would have no effect on Go clients.
+package rpcretry
+
+import (
+	"encoding/json"
+
+	"google.golang.org/grpc"
+)
+
+// nodeServices are the read-only query services served by pd.
+var nodeServices = []string{
+	"penumbra.cnidarium.v1.QueryService",
+	"penumbra.core.app.v1.QueryService",
+	"penumbra.core.component.auction.v1.QueryService",
+	"penumbra.core.component.community_pool.v1.QueryService",
+	"penumbra.core.component.compact_block.v1.QueryService",
+	"penumbra.core.component.dex.v1.QueryService",
+	"penumbra.core.component.dex.v1.SimulationService",
+	"penumbra.core.component.fee.v1.QueryService",
+	"penumbra.core.component.governance.v1.QueryService",
+	"penumbra.core.component.sct.v1.QueryService",
+	"penumbra.core.component.shielded_pool.v1.QueryService",
+	"penumbra.core.component.stake.v1.QueryService",
+	"penumbra.util.tendermint_proxy.v1.TendermintProxyService",
+}
+
+// noRetry are methods of the retried services that must not be repeated.
+var noRetry = []name{
+	{"penumbra.util.tendermint_proxy.v1.TendermintProxyService", "BroadcastTxAsync"},
+	{"penumbra.util.tendermint_proxy.v1.TendermintProxyService", "BroadcastTxSync"},
+	{"penumbra.view.v1.ViewService", "AuthorizeAndBuild"},
+	{"penumbra.view.v1.ViewService", "BroadcastTransaction"},
+}
+
+type name struct {
+	Service string `json:"service"`
+	Method  string `json:"method,omitempty"`
+}
+
+type retryPolicy struct {
+	MaxAttempts          int      `json:"maxAttempts"`
+	InitialBackoff       string   `json:"initialBackoff"`
+	MaxBackoff           string   `json:"maxBackoff"`
+	BackoffMultiplier    float64  `json:"backoffMultiplier"`
+	RetryableStatusCodes []string `json:"retryableStatusCodes"`
+}
+
+type methodConfig struct {
+	Name        []name       `json:"name"`
+	RetryPolicy *retryPolicy `json:"retryPolicy,omitempty"`
+}
+
+type retryThrottling struct {
+	MaxTokens  int     `json:"maxTokens"`
+	TokenRatio float64 `json:"tokenRatio"`
+}
+
+type serviceConfig struct {
+	MethodConfig    []methodConfig   `json:"methodConfig"`
+	RetryThrottling *retryThrottling `json:"retryThrottling"`
+}
+
+func config() string {
+	var node []name
+	for _, s := range nodeServices {
+		node = append(node, name{Service: s})
+	}
+	sc := serviceConfig{
+		MethodConfig: []methodConfig{
+			{
+				Name: node,
+				RetryPolicy: &retryPolicy{
+					MaxAttempts:          4,
+					InitialBackoff:       "0.2s",
+					MaxBackoff:           "5s",
+					BackoffMultiplier:    2,
+					RetryableStatusCodes: []string{"UNAVAILABLE", "RESOURCE_EXHAUSTED"},
+				},
+			},
+			{
+				Name: []name{{Service: "penumbra.view.v1.ViewService"}},
+				RetryPolicy: &retryPolicy{
+					MaxAttempts:          3,
+					InitialBackoff:       "0.1s",
+					MaxBackoff:           "1s",
+					BackoffMultiplier:    2,
+					RetryableStatusCodes: []string{"UNAVAILABLE"},
+				},
+			},
+			// A method entry takes precedence over its service, so these
+			// methods get no policy.
+			{Name: noRetry},
+		},
+		// Stop retrying after a run of failures, so that retries do not add
+		// load to a server that is already down.
+		RetryThrottling: &retryThrottling{MaxTokens: 10, TokenRatio: 0.1},
+	}
+	b, err := json.Marshal(sc)
+	if err != nil {
+		panic(err)
+	}
+	return string(b)
+}
+
+// ServiceConfig is the service config applied by DialOptions, in the JSON
+// form accepted by grpc.WithDefaultServiceConfig.
+var ServiceConfig = config()
+
+// DialOptions returns the options that apply ServiceConfig to a client
+// connection.
+func DialOptions() []grpc.DialOption {
+	return []grpc.DialOption{grpc.WithDefaultServiceConfig(ServiceConfig)}
+}
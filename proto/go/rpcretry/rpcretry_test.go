@@ -0,0 +1,62 @@
+package rpcretry
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+
+	"github.com/penumbra-zone/penumbra/proto/go/inprocess"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// flaky registers a unary method that fails with UNAVAILABLE until it has
+// been called fail times.
+func flaky(s grpc.ServiceRegistrar, service, method string, fail int32, calls *atomic.Int32) {
+	s.RegisterService(&grpc.ServiceDesc{
+		ServiceName: service,
+		HandlerType: (*any)(nil),
+		Methods: []grpc.MethodDesc{{
+			MethodName: method,
+			Handler: func(_ any, ctx context.Context, dec func(any) error, _ grpc.UnaryServerInterceptor) (any, error) {
+				if err := dec(new(emptypb.Empty)); err != nil {
+					return nil, err
+				}
+				if calls.Add(1) <= fail {
+					return nil, status.Error(codes.Unavailable, "try again")
+				}
+				return new(emptypb.Empty), nil
+			},
+		}},
+	}, struct{}{})
+}
+
+func TestRetry(t *testing.T) {
+	for _, tt := range []struct {
+		service, method string
+		fail            int32
+		wantCalls       int32
+		wantCode        codes.Code
+	}{
+		{"penumbra.core.component.fee.v1.QueryService", "CurrentGasPrices", 2, 3, codes.OK},
+		{"penumbra.core.component.fee.v1.QueryService", "CurrentGasPrices", 10, 4, codes.Unavailable},
+		{"penumbra.view.v1.ViewService", "Balances", 2, 3, codes.OK},
+		{"penumbra.view.v1.ViewService", "BroadcastTransaction", 1, 1, codes.Unavailable},
+		{"penumbra.custody.v1.CustodyService", "Authorize", 1, 1, codes.Unavailable},
+	} {
+		var calls atomic.Int32
+		conn, err := inprocess.New(func(s grpc.ServiceRegistrar) {
+			flaky(s, tt.service, tt.method, tt.fail, &calls)
+		}, nil, DialOptions()...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = conn.Invoke(context.Background(), "/"+tt.service+"/"+tt.method, new(emptypb.Empty), new(emptypb.Empty))
+		conn.Close()
+		if status.Code(err) != tt.wantCode || calls.Load() != tt.wantCalls {
+			t.Errorf("%s/%s: err = %v after %d calls, want %v after %d", tt.service, tt.method, err, calls.Load(), tt.wantCode, tt.wantCalls)
+		}
+	}
+}
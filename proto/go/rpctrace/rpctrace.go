@@ -0,0 +1,148 @@
+// Package rpctrace traces the custody, view and node RPCs with OpenTelemetry.
+//
+// Spans and trace context propagation come from otelgrpc's stats handlers,
+// so a trace started in a wallet follows the call chain from custody to the
+// view server to the node, as long as each hop uses these options and the
+// process has a propagator installed (see otel.SetTextMapPropagator). The
+// interceptors here add Penumbra-specific attributes to those spans, taken
+// from the top-level fields of each request:
+//
+//   - penumbra.account, from an address_index or account_filter field
+//   - penumbra.wallet_id, from a wallet_id field, as a Bech32m string
+//   - penumbra.plan.actions, the number of actions in a plan field
+//   - penumbra.start_height and penumbra.end_height, from fields of those names
+package rpctrace
+
+import (
+	"context"
+
+	"github.com/penumbra-zone/penumbra/proto/go/bech32m"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+const (
+	addressIndexName = "penumbra.core.keys.v1.AddressIndex"
+	walletIDName     = "penumbra.core.keys.v1.WalletId"
+	planName         = "penumbra.core.transaction.v1.TransactionPlan"
+)
+
+// ServerOptions returns the options that trace every RPC served by a
+// grpc.Server. The otelgrpc options configure the tracer provider,
+// propagators and filters.
+func ServerOptions(opts ...otelgrpc.Option) []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.StatsHandler(otelgrpc.NewServerHandler(opts...)),
+		grpc.ChainUnaryInterceptor(func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+			annotate(ctx, req)
+			return handler(ctx, req)
+		}),
+		grpc.ChainStreamInterceptor(func(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			return handler(srv, &serverStream{ServerStream: ss})
+		}),
+	}
+}
+
+// DialOptions returns the options that trace every RPC made on a client
+// connection.
+func DialOptions(opts ...otelgrpc.Option) []grpc.DialOption {
+	return []grpc.DialOption{
+		grpc.WithStatsHandler(otelgrpc.NewClientHandler(opts...)),
+		grpc.WithChainUnaryInterceptor(func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+			annotate(ctx, req)
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}),
+		grpc.WithChainStreamInterceptor(func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+			cs, err := streamer(ctx, desc, cc, method, opts...)
+			if err != nil {
+				return nil, err
+			}
+			return &clientStream{ClientStream: cs}, nil
+		}),
+	}
+}
+
+// serverStream annotates the span with the first request received on a
+// stream, which carries the parameters of server-streaming RPCs.
+type serverStream struct {
+	grpc.ServerStream
+	annotated bool
+}
+
+func (s *serverStream) RecvMsg(m any) error {
+	err := s.ServerStream.RecvMsg(m)
+	if err == nil && !s.annotated {
+		annotate(s.Context(), m)
+		s.annotated = true
+	}
+	return err
+}
+
+type clientStream struct {
+	grpc.ClientStream
+	annotated bool
+}
+
+func (s *clientStream) SendMsg(m any) error {
+	if !s.annotated {
+		annotate(s.Context(), m)
+		s.annotated = true
+	}
+	return s.ClientStream.SendMsg(m)
+}
+
+func annotate(ctx context.Context, req any) {
+	span := trace.SpanFromContext(ctx)
+	if !span.IsRecording() {
+		return
+	}
+	m, ok := req.(proto.Message)
+	if !ok {
+		return
+	}
+	span.SetAttributes(Attributes(m)...)
+}
+
+// Attributes returns the Penumbra-specific span attributes for a request.
+func Attributes(req proto.Message) []attribute.KeyValue {
+	var attrs []attribute.KeyValue
+	r := req.ProtoReflect()
+	r.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		if fd.IsList() || fd.IsMap() {
+			return true
+		}
+		switch fd.Name() {
+		case "start_height", "end_height":
+			if fd.Kind() == protoreflect.Uint64Kind {
+				attrs = append(attrs, attribute.Int64("penumbra."+string(fd.Name()), int64(v.Uint())))
+			}
+			return true
+		}
+		md := fd.Message()
+		if md == nil {
+			return true
+		}
+		switch md.FullName() {
+		case addressIndexName:
+			if account := md.Fields().ByName("account"); account != nil {
+				attrs = append(attrs, attribute.Int64("penumbra.account", int64(v.Message().Get(account).Uint())))
+			}
+		case walletIDName:
+			if inner := md.Fields().ByName("inner"); inner != nil {
+				if s, err := bech32m.Encode(bech32m.WalletIDPrefix, v.Message().Get(inner).Bytes()); err == nil {
+					attrs = append(attrs, attribute.String("penumbra.wallet_id", s))
+				}
+			}
+		case planName:
+			if actions := md.Fields().ByName("actions"); actions != nil {
+				attrs = append(attrs, attribute.Int("penumbra.plan.actions", v.Message().Get(actions).List().Len()))
+			}
+		}
+		return true
+	})
+	return attrs
+}
@@ -0,0 +1,155 @@
+package rpctrace
+
+import (
+	"context"
+	"testing"
+
+	"github.com/penumbra-zone/penumbra/proto/go/inprocess"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// Minimal copies of the Penumbra messages Attributes reads, and a request
+// that carries all of them.
+var testFiles = []string{`
+name: "penumbra/core/keys/v1/keys.proto"
+package: "penumbra.core.keys.v1"
+syntax: "proto3"
+message_type: {
+  name: "AddressIndex"
+  field: {name: "account" number: 2 type: TYPE_UINT32 label: LABEL_OPTIONAL json_name: "account"}
+  field: {name: "randomizer" number: 3 type: TYPE_BYTES label: LABEL_OPTIONAL json_name: "randomizer"}
+}
+message_type: {
+  name: "WalletId"
+  field: {name: "inner" number: 1 type: TYPE_BYTES label: LABEL_OPTIONAL json_name: "inner"}
+}`, `
+name: "penumbra/core/transaction/v1/transaction.proto"
+package: "penumbra.core.transaction.v1"
+syntax: "proto3"
+message_type: {name: "ActionPlan"}
+message_type: {
+  name: "TransactionPlan"
+  field: {name: "actions" number: 1 type: TYPE_MESSAGE type_name: ".penumbra.core.transaction.v1.ActionPlan" label: LABEL_REPEATED json_name: "actions"}
+}`, `
+name: "rpctracetest/v1/test.proto"
+package: "rpctracetest.v1"
+syntax: "proto3"
+dependency: ["penumbra/core/keys/v1/keys.proto", "penumbra/core/transaction/v1/transaction.proto"]
+message_type: {
+  name: "Request"
+  field: {name: "address_index" number: 1 type: TYPE_MESSAGE type_name: ".penumbra.core.keys.v1.AddressIndex" label: LABEL_OPTIONAL json_name: "addressIndex"}
+  field: {name: "wallet_id" number: 2 type: TYPE_MESSAGE type_name: ".penumbra.core.keys.v1.WalletId" label: LABEL_OPTIONAL json_name: "walletId"}
+  field: {name: "plan" number: 3 type: TYPE_MESSAGE type_name: ".penumbra.core.transaction.v1.TransactionPlan" label: LABEL_OPTIONAL json_name: "plan"}
+  field: {name: "start_height" number: 4 type: TYPE_UINT64 label: LABEL_OPTIONAL json_name: "startHeight"}
+  field: {name: "end_height" number: 5 type: TYPE_UINT64 label: LABEL_OPTIONAL json_name: "endHeight"}
+}`}
+
+func testRequest(t *testing.T, json string) *dynamicpb.Message {
+	t.Helper()
+	files := new(protoregistry.Files)
+	for _, text := range testFiles {
+		fdp := new(descriptorpb.FileDescriptorProto)
+		if err := prototext.Unmarshal([]byte(text), fdp); err != nil {
+			t.Fatal(err)
+		}
+		fd, err := protodesc.NewFile(fdp, files)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := files.RegisterFile(fd); err != nil {
+			t.Fatal(err)
+		}
+	}
+	d, err := files.FindDescriptorByName("rpctracetest.v1.Request")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := dynamicpb.NewMessage(d.(protoreflect.MessageDescriptor))
+	if err := protojson.Unmarshal([]byte(json), m); err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestAttributes(t *testing.T) {
+	req := testRequest(t, `{
+		"addressIndex": {"account": 3},
+		"walletId": {"inner": "AAECAwQFBgcICQoLDA0ODxAREhMUFRYXGBkaGxwdHh8="},
+		"plan": {"actions": [{}, {}]},
+		"startHeight": "10",
+		"endHeight": "20"
+	}`)
+	got := map[attribute.Key]attribute.Value{}
+	for _, kv := range Attributes(req) {
+		got[kv.Key] = kv.Value
+	}
+	want := map[attribute.Key]attribute.Value{
+		"penumbra.account":      attribute.Int64Value(3),
+		"penumbra.plan.actions": attribute.IntValue(2),
+		"penumbra.start_height": attribute.Int64Value(10),
+		"penumbra.end_height":   attribute.Int64Value(20),
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("%s = %v, want %v", k, got[k].Emit(), v.Emit())
+		}
+	}
+	if id := got["penumbra.wallet_id"].AsString(); len(id) < 17 || id[:17] != "penumbrawalletid1" {
+		t.Errorf("penumbra.wallet_id = %q, want a penumbrawalletid1 string", id)
+	}
+
+	if attrs := Attributes(testRequest(t, `{}`)); len(attrs) != 0 {
+		t.Errorf("Attributes of an empty request = %v, want none", attrs)
+	}
+}
+
+func TestPropagation(t *testing.T) {
+	rec := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec))
+	opts := []otelgrpc.Option{
+		otelgrpc.WithTracerProvider(tp),
+		otelgrpc.WithPropagators(propagation.TraceContext{}),
+	}
+	conn, err := inprocess.New(func(s grpc.ServiceRegistrar) {
+		healthpb.RegisterHealthServer(s, health.NewServer())
+	}, ServerOptions(opts...), DialOptions(opts...)...)
+	if err != nil {
+		t.Fatalf("inprocess.New: %v", err)
+	}
+	defer conn.Close()
+
+	if _, err := healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{}); err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	conn.Close()
+
+	spans := rec.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("recorded %d spans, want 2", len(spans))
+	}
+	server, client := spans[0], spans[1]
+	if server.SpanKind() != trace.SpanKindServer {
+		server, client = client, server
+	}
+	if server.Parent().SpanID() != client.SpanContext().SpanID() {
+		t.Fatalf("server span parent = %v, want client span %v", server.Parent().SpanID(), client.SpanContext().SpanID())
+	}
+	if server.Name() != "grpc.health.v1.Health/Check" {
+		t.Fatalf("server span name = %q", server.Name())
+	}
+}
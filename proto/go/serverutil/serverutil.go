@@ -0,0 +1,145 @@
+// Package serverutil runs a grpc.Server for the custody, view or node
+// services with the setup every deployment needs: a listener, optional TLS,
+// the health and reflection services, and graceful shutdown on SIGINT or
+// SIGTERM.
+//
+//	err := serverutil.Run(ctx, serverutil.Config{
+//		Addr: "127.0.0.1:8081",
+//		Register: func(s grpc.ServiceRegistrar) {
+//			viewv1.RegisterViewServiceServer(s, view)
+//		},
+//		Reflection: true,
+//	})
+package serverutil
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/penumbra-zone/penumbra/proto/go/localrpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+)
+
+// DefaultShutdownTimeout is how long Run waits for RPCs in flight to finish
+// before closing their connections.
+const DefaultShutdownTimeout = 10 * time.Second
+
+// Config configures Run.
+type Config struct {
+	// Addr is a TCP address ("host:port"), or "unix://" followed by the
+	// path of a Unix domain socket, which is created with mode 0o600 (see
+	// localrpc.Listen). It is ignored if Listener is set.
+	Addr string
+	// Listener, if set, is served instead of listening on Addr.
+	Listener net.Listener
+	// TLS, if set, is used for TCP connections. Unix domain sockets use
+	// local credentials instead.
+	TLS *tls.Config
+	// Register adds the services to the server.
+	Register func(grpc.ServiceRegistrar)
+	// ServerOptions are passed to grpc.NewServer, for interceptors and
+	// limits. They are applied after the transport credentials.
+	ServerOptions []grpc.ServerOption
+	// Reflection registers the server reflection service, which lets tools
+	// such as grpcurl discover the services without their descriptors.
+	Reflection bool
+	// ShutdownTimeout is how long to wait for RPCs in flight to finish on
+	// shutdown. If zero, DefaultShutdownTimeout is used.
+	ShutdownTimeout time.Duration
+}
+
+// Run serves until ctx is done or the process receives SIGINT or SIGTERM,
+// then shuts down gracefully: the health service reports NOT_SERVING, new
+// RPCs are refused, and RPCs in flight, including streams, get
+// ShutdownTimeout to finish before their connections are closed. Run
+// returns nil after a shutdown, and the error otherwise.
+//
+// Every registered service, and the server as a whole (the empty service
+// name), reports SERVING through the standard health service.
+func Run(ctx context.Context, cfg Config) error {
+	if cfg.Register == nil {
+		return errors.New("serverutil: no services to register")
+	}
+	lis, local, err := listen(cfg)
+	if err != nil {
+		return err
+	}
+
+	var opts []grpc.ServerOption
+	switch {
+	case local:
+		opts = localrpc.ServerOptions()
+	case cfg.TLS != nil:
+		opts = []grpc.ServerOption{grpc.Creds(credentials.NewTLS(cfg.TLS))}
+	}
+	s := grpc.NewServer(append(opts, cfg.ServerOptions...)...)
+	cfg.Register(s)
+
+	hs := health.NewServer()
+	for name := range s.GetServiceInfo() {
+		hs.SetServingStatus(name, healthpb.HealthCheckResponse_SERVING)
+	}
+	healthpb.RegisterHealthServer(s, hs)
+	if cfg.Reflection {
+		reflection.Register(s)
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	errc := make(chan error, 1)
+	go func() { errc <- s.Serve(lis) }()
+
+	select {
+	case err := <-errc:
+		return fmt.Errorf("serverutil: %w", err)
+	case <-ctx.Done():
+	}
+
+	hs.Shutdown()
+	timeout := cfg.ShutdownTimeout
+	if timeout == 0 {
+		timeout = DefaultShutdownTimeout
+	}
+	drained := make(chan struct{})
+	go func() {
+		s.GracefulStop()
+		close(drained)
+	}()
+	select {
+	case <-drained:
+	case <-time.After(timeout):
+		s.Stop()
+		<-drained
+	}
+	<-errc
+	return nil
+}
+
+func listen(cfg Config) (net.Listener, bool, error) {
+	if cfg.Listener != nil {
+		_, local := cfg.Listener.Addr().(*net.UnixAddr)
+		return cfg.Listener, local, nil
+	}
+	if path, ok := strings.CutPrefix(cfg.Addr, "unix://"); ok {
+		lis, err := localrpc.Listen(path, 0o600)
+		return lis, true, err
+	}
+	lis, err := net.Listen("tcp", cfg.Addr)
+	if err != nil {
+		return nil, false, fmt.Errorf("serverutil: %w", err)
+	}
+	return lis, false, nil
+}
@@ -0,0 +1,98 @@
+package serverutil
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/penumbra-zone/penumbra/proto/go/localrpc"
+	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	testgrpc "google.golang.org/grpc/interop/grpc_testing"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+)
+
+func TestRun(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "view.sock")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		done <- Run(ctx, Config{
+			Addr: "unix://" + path,
+			Register: func(s grpc.ServiceRegistrar) {
+				testgrpc.RegisterTestServiceServer(s, testgrpc.UnimplementedTestServiceServer{})
+			},
+			Reflection:      true,
+			ShutdownTimeout: 100 * time.Millisecond,
+		})
+	}()
+
+	conn, err := localrpc.Dial(path)
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	defer conn.Close()
+
+	// The socket appears asynchronously; wait for the server to be ready.
+	health := healthpb.NewHealthClient(conn)
+	watch, err := health.Watch(context.Background(), &healthpb.HealthCheckRequest{Service: "grpc.testing.TestService"}, grpc.WaitForReady(true))
+	if err != nil {
+		t.Fatalf("Watch: %v", err)
+	}
+	resp, err := watch.Recv()
+	if err != nil {
+		t.Fatalf("Recv: %v", err)
+	}
+	if resp.Status != healthpb.HealthCheckResponse_SERVING {
+		t.Fatalf("status = %v, want SERVING", resp.Status)
+	}
+
+	refl, err := reflectionpb.NewServerReflectionClient(conn).ServerReflectionInfo(context.Background())
+	if err != nil {
+		t.Fatalf("ServerReflectionInfo: %v", err)
+	}
+	if err := refl.Send(&reflectionpb.ServerReflectionRequest{
+		MessageRequest: &reflectionpb.ServerReflectionRequest_ListServices{},
+	}); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	list, err := refl.Recv()
+	if err != nil {
+		t.Fatalf("Recv: %v", err)
+	}
+	services := map[string]bool{}
+	for _, s := range list.GetListServicesResponse().GetService() {
+		services[s.Name] = true
+	}
+	if !services["grpc.testing.TestService"] || !services["grpc.health.v1.Health"] {
+		t.Errorf("reflection lists %v", services)
+	}
+
+	// On shutdown, the health service reports NOT_SERVING, then the watch,
+	// which never ends by itself, is cut off after the shutdown timeout.
+	start := time.Now()
+	cancel()
+	if resp, err := watch.Recv(); err != nil || resp.Status != healthpb.HealthCheckResponse_NOT_SERVING {
+		t.Errorf("Recv after shutdown = %v, %v, want NOT_SERVING", resp, err)
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("shutdown took %v", elapsed)
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	if err := Run(context.Background(), Config{Addr: "127.0.0.1:0"}); err == nil {
+		t.Error("Run without services: want error")
+	}
+	register := func(s grpc.ServiceRegistrar) {
+		testgrpc.RegisterTestServiceServer(s, testgrpc.UnimplementedTestServiceServer{})
+	}
+	if err := Run(context.Background(), Config{Addr: "256.0.0.1:0", Register: register}); err == nil {
+		t.Error("Run with an invalid address: want error")
+	}
+}
@@ -0,0 +1,124 @@
+// Package strictproto decodes messages that must be understood in full
+// before they are acted on.
+//
+// proto.Unmarshal keeps fields it does not recognize, and proto3 enums
+// accept values they do not declare. Both are right for forward
+// compatibility, but wrong for a custodian: a plan built against a newer
+// schema can carry an action or field that changes its meaning, and a
+// custodian that skips what it cannot parse would sign it anyway. Strict
+// decoding rejects such messages instead.
+//
+// On a custody server, the interceptor applies the check to the
+// authorization requests:
+//
+//	s := grpc.NewServer(grpc.ChainUnaryInterceptor(strictproto.UnaryServerInterceptor()))
+package strictproto
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// ErrUnknown is wrapped by the errors returned for unknown fields, unknown
+// enum values, and Any values of unknown type.
+var ErrUnknown = errors.New("strictproto: unknown content")
+
+// Sensitive are the requests checked by UnaryServerInterceptor by default:
+// the custody requests that ask for a signature. Pre-authorizations are
+// checked as part of them.
+var Sensitive = []protoreflect.FullName{
+	"penumbra.custody.v1.AuthorizeRequest",
+	"penumbra.custody.v1.AuthorizeValidatorDefinitionRequest",
+	"penumbra.custody.v1.AuthorizeValidatorVoteRequest",
+}
+
+// Unmarshal decodes b into m, and fails if any part of it is not described
+// by m's schema.
+func Unmarshal(b []byte, m proto.Message) error {
+	if err := proto.Unmarshal(b, m); err != nil {
+		return err
+	}
+	return Check(m)
+}
+
+// Check returns an error naming the first part of m, or of any message in
+// it, that is not described by its schema: an unknown field, an undeclared
+// enum value, or an Any whose type is not linked into the binary.
+func Check(m proto.Message) error {
+	return check(m.ProtoReflect(), string(m.ProtoReflect().Descriptor().FullName()))
+}
+
+func check(m protoreflect.Message, path string) error {
+	if len(m.GetUnknown()) > 0 {
+		return fmt.Errorf("%w: %s has unknown fields", ErrUnknown, path)
+	}
+	if a, ok := m.Interface().(*anypb.Any); ok {
+		inner, err := a.UnmarshalNew()
+		if err != nil {
+			return fmt.Errorf("%w: %s: %v", ErrUnknown, path, err)
+		}
+		return check(inner.ProtoReflect(), path+"("+string(inner.ProtoReflect().Descriptor().FullName())+")")
+	}
+	var err error
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		p := path + "." + string(fd.Name())
+		switch {
+		case fd.IsMap():
+			v.Map().Range(func(k protoreflect.MapKey, v protoreflect.Value) bool {
+				err = checkValue(fd.MapValue(), v, p+"["+k.String()+"]")
+				return err == nil
+			})
+		case fd.IsList():
+			list := v.List()
+			for i := 0; i < list.Len() && err == nil; i++ {
+				err = checkValue(fd, list.Get(i), p+"["+strconv.Itoa(i)+"]")
+			}
+		default:
+			err = checkValue(fd, v, p)
+		}
+		return err == nil
+	})
+	return err
+}
+
+func checkValue(fd protoreflect.FieldDescriptor, v protoreflect.Value, path string) error {
+	switch {
+	case fd.Message() != nil:
+		return check(v.Message(), path)
+	case fd.Enum() != nil:
+		if fd.Enum().Values().ByNumber(v.Enum()) == nil {
+			return fmt.Errorf("%w: %s has undeclared value %d", ErrUnknown, path, v.Enum())
+		}
+	}
+	return nil
+}
+
+// UnaryServerInterceptor rejects requests of the named types, or of the
+// Sensitive types if none are named, that fail Check. It returns
+// InvalidArgument before the handler runs.
+func UnaryServerInterceptor(names ...protoreflect.FullName) grpc.UnaryServerInterceptor {
+	if len(names) == 0 {
+		names = Sensitive
+	}
+	strict := make(map[protoreflect.FullName]bool, len(names))
+	for _, n := range names {
+		strict[n] = true
+	}
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if m, ok := req.(proto.Message); ok && strict[m.ProtoReflect().Descriptor().FullName()] {
+			if err := Check(m); err != nil {
+				return nil, status.Error(codes.InvalidArgument, err.Error())
+			}
+		}
+		return handler(ctx, req)
+	}
+}
@@ -0,0 +1,97 @@
+package strictproto
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/penumbra-zone/penumbra/proto/go/inprocess"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/typepb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// withUnknown returns the encoding of m with an extra field 99.
+func withUnknown(t *testing.T, m proto.Message) []byte {
+	t.Helper()
+	b, err := proto.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b = protowire.AppendTag(b, 99, protowire.VarintType)
+	return protowire.AppendVarint(b, 1)
+}
+
+func TestUnmarshal(t *testing.T) {
+	known, err := anypb.New(wrapperspb.String("ok"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	valid := &typepb.Type{
+		Name:    "penumbra.core.transaction.v1.TransactionPlan",
+		Fields:  []*typepb.Field{{Name: "actions", Kind: typepb.Field_TYPE_MESSAGE}},
+		Options: []*typepb.Option{{Name: "o", Value: known}},
+		Syntax:  typepb.Syntax_SYNTAX_PROTO3,
+	}
+	b, err := proto.Marshal(valid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Unmarshal(b, new(typepb.Type)); err != nil {
+		t.Errorf("Unmarshal(valid): %v", err)
+	}
+
+	nestedUnknown := proto.Clone(valid).(*typepb.Type)
+	nestedUnknown.Fields[0].ProtoReflect().SetUnknown(protowire.AppendVarint(protowire.AppendTag(nil, 99, protowire.VarintType), 1))
+	undeclaredEnum := proto.Clone(valid).(*typepb.Type)
+	undeclaredEnum.Fields[0].Kind = 100
+	unknownAny := proto.Clone(valid).(*typepb.Type)
+	unknownAny.Options[0].Value = &anypb.Any{TypeUrl: "type.googleapis.com/penumbra.core.transaction.v1.ActionPlan"}
+	anyWithUnknown := proto.Clone(valid).(*typepb.Type)
+	anyWithUnknown.Options[0].Value.Value = withUnknown(t, wrapperspb.String("ok"))
+
+	for name, m := range map[string]*typepb.Type{
+		"nested unknown field":  nestedUnknown,
+		"undeclared enum value": undeclaredEnum,
+		"unresolvable Any":      unknownAny,
+		"unknown field in Any":  anyWithUnknown,
+	} {
+		b, err := proto.Marshal(m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := Unmarshal(b, new(typepb.Type)); !errors.Is(err, ErrUnknown) {
+			t.Errorf("Unmarshal(%s): err = %v, want ErrUnknown", name, err)
+		}
+	}
+	if err := Unmarshal(withUnknown(t, valid), new(typepb.Type)); !errors.Is(err, ErrUnknown) {
+		t.Errorf("Unmarshal(top-level unknown field): err = %v, want ErrUnknown", err)
+	}
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	conn, err := inprocess.New(func(s grpc.ServiceRegistrar) {
+		healthpb.RegisterHealthServer(s, health.NewServer())
+	}, []grpc.ServerOption{grpc.ChainUnaryInterceptor(UnaryServerInterceptor("grpc.health.v1.HealthCheckRequest"))})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	client := healthpb.NewHealthClient(conn)
+
+	if _, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{}); err != nil {
+		t.Errorf("Check: %v", err)
+	}
+	req := &healthpb.HealthCheckRequest{}
+	req.ProtoReflect().SetUnknown(protowire.AppendVarint(protowire.AppendTag(nil, 99, protowire.VarintType), 1))
+	if _, err := client.Check(context.Background(), req); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Check with an unknown field: err = %v, want InvalidArgument", err)
+	}
+}
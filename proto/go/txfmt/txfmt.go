@@ -0,0 +1,246 @@
+// Package txfmt renders the actions of a transaction or transaction plan as
+// a normalized tree, for block explorers and approval screens.
+//
+// It works on any message with the Penumbra descriptors, generated or
+// dynamic, so it can be used on bytes alone:
+//
+//	actions, err := txfmt.Decode("/penumbra.core.transaction.v1.TransactionPlan", b)
+//	...
+//	fmt.Print(txfmt.Format(actions))
+//
+// Values are normalized the way the Rust tooling displays them: addresses,
+// asset IDs, validator keys, position IDs and auction IDs are Bech32m
+// strings, amounts are decimals, other bytes are hex and enums are value
+// names. Any values are expanded when their type is in the descriptors
+// package, and shown as a type URL and hex bytes otherwise.
+package txfmt
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/penumbra-zone/penumbra/proto/go/bech32m"
+	"github.com/penumbra-zone/penumbra/proto/go/descriptors"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+const (
+	transactionName     = "penumbra.core.transaction.v1.Transaction"
+	transactionBodyName = "penumbra.core.transaction.v1.TransactionBody"
+	transactionPlanName = "penumbra.core.transaction.v1.TransactionPlan"
+)
+
+// Field is a node of the rendered tree. A field holding a message has
+// Fields and no Value; any other field has a Value. Repeated fields appear
+// once per element, with the index in the name: "outputs[0]".
+type Field struct {
+	Name   string
+	Value  string
+	Fields []Field
+}
+
+// Action is one action of a transaction or plan.
+type Action struct {
+	// Kind is the name of the action in the Action or ActionPlan oneof,
+	// such as "spend" or "ics20_withdrawal".
+	Kind string
+	// Type is the full name of the action message.
+	Type protoreflect.FullName
+	// Fields are the populated fields of the action message.
+	Fields []Field
+}
+
+// Decode decodes b as the message named by typeURL, which must be a
+// Transaction, TransactionBody or TransactionPlan, and returns its actions.
+func Decode(typeURL string, b []byte) ([]Action, error) {
+	m, err := descriptors.Decode(typeURL, b)
+	if err != nil {
+		return nil, err
+	}
+	return Actions(m)
+}
+
+// Actions returns the actions of m, which must be a Transaction,
+// TransactionBody or TransactionPlan.
+func Actions(m proto.Message) ([]Action, error) {
+	r := m.ProtoReflect()
+	switch name := r.Descriptor().FullName(); name {
+	case transactionName:
+		r = r.Get(r.Descriptor().Fields().ByName("body")).Message()
+	case transactionBodyName, transactionPlanName:
+	default:
+		return nil, fmt.Errorf("txfmt: %s is not a transaction or plan", name)
+	}
+	list := r.Get(r.Descriptor().Fields().ByName("actions")).List()
+	actions := make([]Action, list.Len())
+	for i := range actions {
+		a := list.Get(i).Message()
+		fd := a.WhichOneof(a.Descriptor().Oneofs().ByName("action"))
+		if fd == nil {
+			// An action added to the protocol after these descriptors were
+			// built is an unknown field of the oneof's parent.
+			actions[i] = Action{Kind: "unknown", Fields: unknownFields(a)}
+			continue
+		}
+		action := a.Get(fd).Message()
+		actions[i] = Action{
+			Kind:   string(fd.Name()),
+			Type:   action.Descriptor().FullName(),
+			Fields: fields(action),
+		}
+	}
+	return actions, nil
+}
+
+// Format renders actions as an indented tree: each action is numbered from
+// 1 and followed by its fields, one per line, as "name: value" or as the
+// name of a message with its own fields indented beneath it.
+func Format(actions []Action) string {
+	var b strings.Builder
+	for i, a := range actions {
+		fmt.Fprintf(&b, "%d. %s\n", i+1, a.Kind)
+		writeFields(&b, a.Fields, 1)
+	}
+	return b.String()
+}
+
+func writeFields(b *strings.Builder, fields []Field, depth int) {
+	indent := strings.Repeat("  ", depth)
+	for _, f := range fields {
+		if f.Fields != nil {
+			fmt.Fprintf(b, "%s%s\n", indent, f.Name)
+			writeFields(b, f.Fields, depth+1)
+		} else {
+			fmt.Fprintf(b, "%s%s: %s\n", indent, f.Name, f.Value)
+		}
+	}
+}
+
+// fields returns the populated fields of m in field number order.
+func fields(m protoreflect.Message) []Field {
+	out := []Field{}
+	fds := m.Descriptor().Fields()
+	for i := 0; i < fds.Len(); i++ {
+		fd := fds.Get(i)
+		if !m.Has(fd) {
+			continue
+		}
+		v := m.Get(fd)
+		name := string(fd.Name())
+		switch {
+		case fd.IsList():
+			list := v.List()
+			for j := 0; j < list.Len(); j++ {
+				out = append(out, field(fmt.Sprintf("%s[%d]", name, j), fd, list.Get(j)))
+			}
+		case fd.IsMap():
+			var keys []protoreflect.MapKey
+			v.Map().Range(func(k protoreflect.MapKey, _ protoreflect.Value) bool {
+				keys = append(keys, k)
+				return true
+			})
+			slices.SortFunc(keys, func(a, b protoreflect.MapKey) int {
+				return strings.Compare(a.String(), b.String())
+			})
+			for _, k := range keys {
+				out = append(out, field(fmt.Sprintf("%s[%s]", name, k.String()), fd.MapValue(), v.Map().Get(k)))
+			}
+		default:
+			out = append(out, field(name, fd, v))
+		}
+	}
+	return append(out, unknownFields(m)...)
+}
+
+func unknownFields(m protoreflect.Message) []Field {
+	if u := m.GetUnknown(); len(u) > 0 {
+		return []Field{{Name: "unknown_fields", Value: hex.EncodeToString(u)}}
+	}
+	return nil
+}
+
+func field(name string, fd protoreflect.FieldDescriptor, v protoreflect.Value) Field {
+	switch fd.Kind() {
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		m := v.Message()
+		if s, ok := scalar(m); ok {
+			return Field{Name: name, Value: s}
+		}
+		if m.Descriptor().FullName() == "google.protobuf.Any" {
+			return Field{Name: name, Fields: anyFields(m)}
+		}
+		return Field{Name: name, Fields: fields(m)}
+	case protoreflect.BytesKind:
+		return Field{Name: name, Value: hex.EncodeToString(v.Bytes())}
+	case protoreflect.EnumKind:
+		if ev := fd.Enum().Values().ByNumber(v.Enum()); ev != nil {
+			return Field{Name: name, Value: string(ev.Name())}
+		}
+		return Field{Name: name, Value: strconv.Itoa(int(v.Enum()))}
+	default:
+		return Field{Name: name, Value: v.String()}
+	}
+}
+
+// bech32Types are the messages shown as a single Bech32m string, with the
+// field holding the raw bytes and the prefix to encode them with.
+var bech32Types = map[protoreflect.FullName]struct {
+	field  protoreflect.Name
+	prefix string
+}{
+	"penumbra.core.keys.v1.Address":                {"inner", bech32m.AddressPrefix},
+	"penumbra.core.asset.v1.AssetId":               {"inner", bech32m.AssetIDPrefix},
+	"penumbra.core.keys.v1.IdentityKey":            {"ik", bech32m.ValidatorIdentityKeyPrefix},
+	"penumbra.core.keys.v1.GovernanceKey":          {"gk", bech32m.ValidatorGovernanceKeyPrefix},
+	"penumbra.core.component.dex.v1.PositionId":    {"inner", bech32m.PositionIDPrefix},
+	"penumbra.core.component.auction.v1.AuctionId": {"inner", bech32m.AuctionIDPrefix},
+}
+
+// scalar renders m as a single value if it is one of the types displayed as
+// a string. Messages that also carry an alternative string form use it
+// when the bytes are empty.
+func scalar(m protoreflect.Message) (string, bool) {
+	md := m.Descriptor()
+	if md.FullName() == "penumbra.core.num.v1.Amount" {
+		lo := m.Get(md.Fields().ByName("lo")).Uint()
+		hi := m.Get(md.Fields().ByName("hi")).Uint()
+		n := new(big.Int).SetUint64(hi)
+		n.Lsh(n, 64).Or(n, new(big.Int).SetUint64(lo))
+		return n.String(), true
+	}
+	t, ok := bech32Types[md.FullName()]
+	if !ok {
+		return "", false
+	}
+	if b := m.Get(md.Fields().ByName(t.field)).Bytes(); len(b) > 0 {
+		s, err := bech32m.Encode(t.prefix, b)
+		if err != nil {
+			return hex.EncodeToString(b), true
+		}
+		return s, true
+	}
+	for _, alt := range []protoreflect.Name{"alt_bech32m", "alt_base_denom"} {
+		if fd := md.Fields().ByName(alt); fd != nil && m.Has(fd) {
+			return m.Get(fd).String(), true
+		}
+	}
+	return "", true
+}
+
+// anyFields expands an Any whose type is in the descriptors package, with
+// the type URL first.
+func anyFields(m protoreflect.Message) []Field {
+	md := m.Descriptor()
+	typeURL := m.Get(md.Fields().ByName("type_url")).String()
+	value := m.Get(md.Fields().ByName("value")).Bytes()
+	head := Field{Name: "@type", Value: typeURL}
+	if inner, err := descriptors.Decode(typeURL, value); err == nil {
+		return append([]Field{head}, fields(inner.ProtoReflect())...)
+	}
+	return []Field{head, {Name: "value", Value: hex.EncodeToString(value)}}
+}
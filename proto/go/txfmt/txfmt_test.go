@@ -0,0 +1,138 @@
+package txfmt
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/penumbra-zone/penumbra/proto/go/bech32m"
+	"github.com/penumbra-zone/penumbra/proto/go/descriptors"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// build returns the binary encoding of a message given in protojson.
+func build(t *testing.T, name, s string) []byte {
+	t.Helper()
+	md, err := descriptors.MessageDescriptor(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := dynamicpb.NewMessage(md)
+	if err := (protojson.UnmarshalOptions{Resolver: descriptors.Types()}).Unmarshal([]byte(s), m); err != nil {
+		t.Fatalf("protojson.Unmarshal(%s): %v", s, err)
+	}
+	b, err := proto.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestFormat(t *testing.T) {
+	plan := build(t, transactionPlanName, `{
+		"actions": [
+			{"output": {
+				"value": {
+					"amount": {"lo": "1000000"},
+					"assetId": {"inner": "AAECAwQFBgcICQoLDA0ODxAREhMUFRYXGBkaGxwdHh8="}
+				},
+				"destAddress": {"inner": "`+strings.Repeat("AAAA", 26)+`AAA="},
+				"rseed": "AQID"
+			}},
+			{"delegate": {
+				"epochIndex": "7",
+				"unbondedAmount": {"lo": "0", "hi": "1"},
+				"delegationAmount": {}
+			}},
+			{"ibcRelayAction": {"rawAction": {
+				"@type": "/ibc.core.client.v1.Height",
+				"revisionNumber": "1",
+				"revisionHeight": "42"
+			}}}
+		]
+	}`)
+	// An Any of a type the descriptors do not include cannot be written in
+	// protojson, so append that action in the wire format.
+	raw, err := proto.Marshal(&anypb.Any{TypeUrl: "/ibc.core.client.v1.MsgUpdateClient", Value: []byte{0x0a, 0x01, 0x01}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	relay := protowire.AppendBytes(protowire.AppendTag(nil, 1, protowire.BytesType), raw)
+	action := protowire.AppendBytes(protowire.AppendTag(nil, 17, protowire.BytesType), relay)
+	plan = protowire.AppendBytes(protowire.AppendTag(plan, 1, protowire.BytesType), action)
+
+	actions, err := Decode(transactionPlanName, plan)
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	address, err := bech32m.Encode(bech32m.AddressPrefix, make([]byte, 80))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `1. output
+  value
+    amount: 1000000
+    asset_id: passet1qqqsyqcyq5rqwzqfpg9scrgwpugpzysnzs23v9ccrydpk8qarc0s0ur7kn
+  dest_address: ` + address + `
+  rseed: 010203
+2. delegate
+  epoch_index: 7
+  unbonded_amount: 18446744073709551616
+  delegation_amount: 0
+3. ibc_relay_action
+  raw_action
+    @type: /ibc.core.client.v1.Height
+    revision_number: 1
+    revision_height: 42
+4. ibc_relay_action
+  raw_action
+    @type: /ibc.core.client.v1.MsgUpdateClient
+    value: 0a0101
+`
+	if got := Format(actions); got != want {
+		t.Errorf("Format =\n%s\nwant\n%s", got, want)
+	}
+	if actions[0].Type != "penumbra.core.component.shielded_pool.v1.OutputPlan" {
+		t.Errorf("actions[0].Type = %s", actions[0].Type)
+	}
+}
+
+func TestTransaction(t *testing.T) {
+	tx := build(t, transactionName, `{
+		"body": {"actions": [{"proposalWithdraw": {"proposal": "3", "reason": "superseded"}}]}
+	}`)
+	actions, err := Decode("type.googleapis.com/"+transactionName, tx)
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	want := "1. proposal_withdraw\n  proposal: 3\n  reason: superseded\n"
+	if got := Format(actions); got != want {
+		t.Errorf("Format = %q, want %q", got, want)
+	}
+}
+
+func TestUnknownAction(t *testing.T) {
+	// An action with a field number the descriptors do not know, as a newer
+	// protocol version would send: field 1 (actions), holding field 999.
+	action := []byte{0xba, 0x3e, 0x00}
+	plan := append([]byte{0x0a, byte(len(action))}, action...)
+	actions, err := Decode(transactionPlanName, plan)
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if len(actions) != 1 || actions[0].Kind != "unknown" {
+		t.Fatalf("Decode = %+v", actions)
+	}
+	if f := actions[0].Fields; len(f) != 1 || f[0].Value != "ba3e00" {
+		t.Errorf("unknown action fields = %+v", f)
+	}
+}
+
+func TestNotATransaction(t *testing.T) {
+	if _, err := Decode("penumbra.core.num.v1.Amount", nil); err == nil {
+		t.Error("Decode of an Amount: want error")
+	}
+}
@@ -0,0 +1,298 @@
+// Package validate checks Penumbra messages against the rules the Rust
+// domain types enforce when they convert from protobuf, so that a Go server
+// rejects malformed keys, addresses and asset IDs on ingress, naming the
+// field at fault, instead of passing them on to fail later.
+//
+//	s := grpc.NewServer(
+//		grpc.ChainUnaryInterceptor(validate.UnaryServerInterceptor()),
+//		grpc.ChainStreamInterceptor(validate.StreamServerInterceptor()),
+//	)
+//
+// The rules cover encodings only: lengths, Bech32m strings, the choice
+// between a bytes field and its alternative string forms, and whether
+// field elements are canonical. Checks that need the decaf377 group, such as
+// whether an address's transmission key is a valid point, are not made.
+// Any two 64-bit halves make a valid 128-bit Amount, so amounts have no
+// rule of their own; a Value must have both an amount and an asset ID.
+package validate
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/penumbra-zone/penumbra/proto/go/bech32m"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// Violation is a field that breaks a rule. Field is the path to it from
+// the validated message, in protobuf field names: "actions[0].spend.note".
+type Violation struct {
+	Field       string
+	Description string
+}
+
+// Error lists the violations found in a message, in field order.
+type Error []Violation
+
+func (e Error) Error() string {
+	parts := make([]string, len(e))
+	for i, v := range e {
+		parts[i] = v.Field + ": " + v.Description
+	}
+	return "validate: " + strings.Join(parts, "; ")
+}
+
+// GRPCStatus returns InvalidArgument with the violations attached as a
+// google.rpc.BadRequest, so that status.FromError and clients in other
+// languages see them field by field.
+func (e Error) GRPCStatus() *status.Status {
+	br := &errdetails.BadRequest{}
+	for _, v := range e {
+		br.FieldViolations = append(br.FieldViolations, &errdetails.BadRequest_FieldViolation{
+			Field:       v.Field,
+			Description: v.Description,
+		})
+	}
+	st := status.New(codes.InvalidArgument, e.Error())
+	if withDetails, err := st.WithDetails(br); err == nil {
+		return withDetails
+	}
+	return st
+}
+
+// Validate checks m and every message in it. It returns nil, or an Error
+// listing every violation.
+func Validate(m proto.Message) error {
+	var errs Error
+	walk(m.ProtoReflect(), "", &errs)
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// rules are the checks for each message type, which report violations by
+// field name relative to the message.
+var rules = map[protoreflect.FullName]func(m protoreflect.Message, report func(field, description string)){
+	"penumbra.core.keys.v1.Address":            address,
+	"penumbra.core.asset.v1.AssetId":           assetID,
+	"penumbra.core.asset.v1.Value":             value,
+	"penumbra.core.keys.v1.AddressIndex":       addressIndex,
+	"penumbra.core.keys.v1.FullViewingKey":     length("inner", 64),
+	"penumbra.core.keys.v1.SpendKey":           length("inner", 32),
+	"penumbra.core.keys.v1.WalletId":           length("inner", 32),
+	"penumbra.core.keys.v1.Diversifier":        length("inner", 16),
+	"penumbra.core.keys.v1.IdentityKey":        length("ik", 32),
+	"penumbra.core.keys.v1.GovernanceKey":      length("gk", 32),
+	"penumbra.core.keys.v1.PayloadKey":         length("inner", 32),
+	"penumbra.core.component.sct.v1.Nullifier": fieldElement("inner"),
+	"penumbra.crypto.tct.v1.StateCommitment":   fieldElement("inner"),
+}
+
+func walk(m protoreflect.Message, path string, errs *Error) {
+	if rule := rules[m.Descriptor().FullName()]; rule != nil {
+		rule(m, func(field, description string) {
+			*errs = append(*errs, Violation{Field: join(path, field), Description: description})
+		})
+	}
+	if a, ok := m.Interface().(*anypb.Any); ok {
+		// Types not linked into the binary cannot be checked; strictproto
+		// rejects them where that matters.
+		if inner, err := a.UnmarshalNew(); err == nil {
+			walk(inner.ProtoReflect(), path, errs)
+		}
+		return
+	}
+	fds := m.Descriptor().Fields()
+	for i := 0; i < fds.Len(); i++ {
+		fd := fds.Get(i)
+		if !m.Has(fd) {
+			continue
+		}
+		p := join(path, string(fd.Name()))
+		switch {
+		case fd.IsMap():
+			if fd.MapValue().Message() == nil {
+				continue
+			}
+			var keys []protoreflect.MapKey
+			m.Get(fd).Map().Range(func(k protoreflect.MapKey, _ protoreflect.Value) bool {
+				keys = append(keys, k)
+				return true
+			})
+			slices.SortFunc(keys, func(a, b protoreflect.MapKey) int {
+				return strings.Compare(a.String(), b.String())
+			})
+			for _, k := range keys {
+				walk(m.Get(fd).Map().Get(k).Message(), p+"["+k.String()+"]", errs)
+			}
+		case fd.Message() == nil:
+		case fd.IsList():
+			list := m.Get(fd).List()
+			for j := 0; j < list.Len(); j++ {
+				walk(list.Get(j).Message(), p+"["+strconv.Itoa(j)+"]", errs)
+			}
+		default:
+			walk(m.Get(fd).Message(), p, errs)
+		}
+	}
+}
+
+func join(path, field string) string {
+	if path == "" {
+		return field
+	}
+	return path + "." + field
+}
+
+func bytesField(m protoreflect.Message, name protoreflect.Name) []byte {
+	return m.Get(m.Descriptor().Fields().ByName(name)).Bytes()
+}
+
+func stringField(m protoreflect.Message, name protoreflect.Name) string {
+	return m.Get(m.Descriptor().Fields().ByName(name)).String()
+}
+
+// length requires the bytes field to have exactly n bytes.
+func length(field protoreflect.Name, n int) func(protoreflect.Message, func(string, string)) {
+	return func(m protoreflect.Message, report func(string, string)) {
+		if got := len(bytesField(m, field)); got != n {
+			report(string(field), fmt.Sprintf("must be %d bytes, not %d", n, got))
+		}
+	}
+}
+
+// q is the modulus of the decaf377 base field, Fq.
+var q, _ = new(big.Int).SetString("8444461749428370424248824938781546531375899335154063827935233455917409239041", 10)
+
+// fieldElement requires the bytes field to be the canonical 32-byte
+// little-endian encoding of an element of Fq.
+func fieldElement(field protoreflect.Name) func(protoreflect.Message, func(string, string)) {
+	return func(m protoreflect.Message, report func(string, string)) {
+		b := bytesField(m, field)
+		if len(b) != 32 {
+			report(string(field), fmt.Sprintf("must be 32 bytes, not %d", len(b)))
+			return
+		}
+		if !canonical(b) {
+			report(string(field), "is not a canonical field element")
+		}
+	}
+}
+
+func canonical(b []byte) bool {
+	be := slices.Clone(b)
+	slices.Reverse(be)
+	return new(big.Int).SetBytes(be).Cmp(q) < 0
+}
+
+func address(m protoreflect.Message, report func(string, string)) {
+	inner, alt := bytesField(m, "inner"), stringField(m, "alt_bech32m")
+	switch {
+	case len(inner) > 0 && alt != "":
+		report("alt_bech32m", "must be empty when inner is set")
+	case len(inner) > 0:
+		if len(inner) != 80 {
+			report("inner", fmt.Sprintf("must be 80 bytes, not %d", len(inner)))
+		}
+	case alt != "":
+		var b []byte
+		var err error
+		if strings.HasPrefix(alt, bech32m.CompatAddressPrefix) {
+			b, err = bech32m.DecodeBech32(bech32m.CompatAddressPrefix, alt)
+		} else {
+			b, err = bech32m.Decode(bech32m.AddressPrefix, alt)
+		}
+		if err != nil {
+			report("alt_bech32m", err.Error())
+		} else if len(b) != 80 {
+			report("alt_bech32m", fmt.Sprintf("must encode 80 bytes, not %d", len(b)))
+		}
+	default:
+		report("inner", "one of inner or alt_bech32m must be set")
+	}
+}
+
+func assetID(m protoreflect.Message, report func(string, string)) {
+	inner := bytesField(m, "inner")
+	alt, denom := stringField(m, "alt_bech32m"), stringField(m, "alt_base_denom")
+	switch {
+	case len(inner) > 0 && (alt != "" || denom != ""):
+		report("inner", "must be the only field set")
+	case len(inner) > 0:
+		fieldElement("inner")(m, report)
+	case alt != "":
+		b, err := bech32m.Decode(bech32m.AssetIDPrefix, alt)
+		switch {
+		case err != nil:
+			report("alt_bech32m", err.Error())
+		case len(b) != 32:
+			report("alt_bech32m", fmt.Sprintf("must encode 32 bytes, not %d", len(b)))
+		case !canonical(b):
+			report("alt_bech32m", "is not a canonical field element")
+		}
+	case denom != "":
+	default:
+		report("inner", "one of inner, alt_bech32m or alt_base_denom must be set")
+	}
+}
+
+func value(m protoreflect.Message, report func(string, string)) {
+	for _, name := range []protoreflect.Name{"amount", "asset_id"} {
+		if !m.Has(m.Descriptor().Fields().ByName(name)) {
+			report(string(name), "must be set")
+		}
+	}
+}
+
+func addressIndex(m protoreflect.Message, report func(string, string)) {
+	if n := len(bytesField(m, "randomizer")); n != 0 && n != 12 {
+		report("randomizer", fmt.Sprintf("must be empty or 12 bytes, not %d", n))
+	}
+}
+
+// UnaryServerInterceptor rejects requests that fail Validate with
+// InvalidArgument, before the handler runs.
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if m, ok := req.(proto.Message); ok {
+			if err := Validate(m); err != nil {
+				return nil, err
+			}
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor validates each message a stream receives. The
+// handler's RecvMsg returns the InvalidArgument error in place of a message
+// that fails Validate.
+func StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, validatingStream{ss})
+	}
+}
+
+type validatingStream struct {
+	grpc.ServerStream
+}
+
+func (s validatingStream) RecvMsg(m any) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	if pm, ok := m.(proto.Message); ok {
+		return Validate(pm)
+	}
+	return nil
+}
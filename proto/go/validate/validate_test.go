@@ -0,0 +1,183 @@
+package validate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/penumbra-zone/penumbra/proto/go/descriptors"
+	"github.com/penumbra-zone/penumbra/proto/go/inprocess"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+const (
+	planName     = "penumbra.core.transaction.v1.TransactionPlan"
+	validAddress = "penumbra147mfall0zr6am5r45qkwht7xqqrdsp50czde7empv7yq2nk3z8yyfh9k9520ddgswkmzar22vhz9dwtuem7uxw0qytfpv7lk3q9dp8ccaw2fn5c838rfackazmgf3ahh09cxmz"
+	// An asset ID from the Rust test suite.
+	validAssetID = "passet1vhga2czmpk76hsu3t7usjj2a2qga0u29vqlcp3hky8lwkfz30qrqy6gaae"
+)
+
+// message returns a dynamic message given in protojson.
+func message(t *testing.T, name, s string) *dynamicpb.Message {
+	t.Helper()
+	md, err := descriptors.MessageDescriptor(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := dynamicpb.NewMessage(md)
+	if err := (protojson.UnmarshalOptions{Resolver: descriptors.Types()}).Unmarshal([]byte(s), m); err != nil {
+		t.Fatalf("protojson.Unmarshal(%s): %v", s, err)
+	}
+	return m
+}
+
+func TestValidate(t *testing.T) {
+	for _, tc := range []struct {
+		name, msg, json string
+		want            []string
+	}{{
+		name: "valid",
+		msg:  planName,
+		json: `{"actions": [{"output": {
+			"value": {"amount": {"lo": "1"}, "assetId": {"altBech32m": "` + validAssetID + `"}},
+			"destAddress": {"altBech32m": "` + validAddress + `"}
+		}}, {"spend": {"note": {
+			"value": {"amount": {}, "assetId": {"altBaseDenom": "upenumbra"}},
+			"address": {"inner": "` + strings.Repeat("AAAA", 26) + `AAA="}
+		}}}]}`,
+	}, {
+		name: "output",
+		msg:  planName,
+		json: `{"actions": [{"output": {
+			"value": {"amount": {"lo": "1"}},
+			"destAddress": {"inner": "AAAA"}
+		}}]}`,
+		want: []string{
+			"actions[0].output.value.asset_id: must be set",
+			"actions[0].output.dest_address.inner: must be 80 bytes, not 3",
+		},
+	}, {
+		name: "address",
+		msg:  "penumbra.core.keys.v1.Address",
+		json: `{"inner": "AAAA", "altBech32m": "` + validAddress + `"}`,
+		want: []string{"alt_bech32m: must be empty when inner is set"},
+	}, {
+		name: "empty address",
+		msg:  "penumbra.core.keys.v1.Address",
+		json: `{}`,
+		want: []string{"inner: one of inner or alt_bech32m must be set"},
+	}, {
+		name: "address checksum",
+		msg:  "penumbra.core.keys.v1.Address",
+		json: `{"altBech32m": "` + validAddress[:len(validAddress)-1] + `q"}`,
+		want: []string{"alt_bech32m: bech32m: invalid checksum"},
+	}, {
+		name: "compat address",
+		msg:  "penumbra.core.keys.v1.Address",
+		json: `{"altBech32m": "penumbracompat11qqqqqqqq"}`,
+		want: []string{"alt_bech32m: bech32m: invalid checksum"},
+	}, {
+		name: "asset id",
+		msg:  "penumbra.core.asset.v1.AssetId",
+		json: `{"inner": "` + strings.Repeat("/", 42) + `8="}`,
+		want: []string{"inner: is not a canonical field element"},
+	}, {
+		name: "asset id with denom",
+		msg:  "penumbra.core.asset.v1.AssetId",
+		json: `{"inner": "AAAA", "altBaseDenom": "upenumbra"}`,
+		want: []string{"inner: must be the only field set"},
+	}, {
+		name: "keys",
+		msg:  "penumbra.custody.v1.AuthorizeRequest",
+		json: `{"preAuthorizations": [{"ed25519": {"vk": "AAAA"}}], "plan": {
+			"memo": {"plaintext": {"returnAddress": {"inner": "AAAA"}}},
+			"actions": [{"delegate": {"validatorIdentity": {"ik": "AAAA"}}}]
+		}}`,
+		want: []string{
+			"plan.actions[0].delegate.validator_identity.ik: must be 32 bytes, not 3",
+			"plan.memo.plaintext.return_address.inner: must be 80 bytes, not 3",
+		},
+	}, {
+		name: "address index",
+		msg:  "penumbra.core.keys.v1.AddressIndex",
+		json: `{"account": 1, "randomizer": "AAAA"}`,
+		want: []string{"randomizer: must be empty or 12 bytes, not 3"},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := Validate(message(t, tc.msg, tc.json))
+			var got []string
+			var verr Error
+			if errors.As(err, &verr) {
+				for _, v := range verr {
+					got = append(got, v.Field+": "+v.Description)
+				}
+			} else if err != nil {
+				t.Fatalf("Validate: %v", err)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tc.want) {
+				t.Errorf("Validate =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tc.want, "\n"))
+			}
+		})
+	}
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	md, err := descriptors.MessageDescriptor("penumbra.core.keys.v1.Address")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var called bool
+	desc := grpc.ServiceDesc{
+		ServiceName: "test.Echo",
+		HandlerType: (*any)(nil),
+		Methods: []grpc.MethodDesc{{
+			MethodName: "Echo",
+			Handler: func(_ any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+				req := dynamicpb.NewMessage(md)
+				if err := dec(req); err != nil {
+					return nil, err
+				}
+				return interceptor(ctx, req, &grpc.UnaryServerInfo{FullMethod: "/test.Echo/Echo"}, func(ctx context.Context, req any) (any, error) {
+					called = true
+					return req, nil
+				})
+			},
+		}},
+	}
+	conn, err := inprocess.New(func(s grpc.ServiceRegistrar) { s.RegisterService(&desc, nil) },
+		[]grpc.ServerOption{grpc.UnaryInterceptor(UnaryServerInterceptor())})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	err = conn.Invoke(context.Background(), "/test.Echo/Echo", message(t, "penumbra.core.keys.v1.Address", `{}`), dynamicpb.NewMessage(md))
+	if called {
+		t.Error("handler called with an invalid request")
+	}
+	st := status.Convert(err)
+	if st.Code() != codes.InvalidArgument {
+		t.Fatalf("Invoke = %v, want InvalidArgument", err)
+	}
+	var br *errdetails.BadRequest
+	for _, d := range st.Details() {
+		if d, ok := d.(*errdetails.BadRequest); ok {
+			br = d
+		}
+	}
+	if br == nil || len(br.FieldViolations) != 1 || br.FieldViolations[0].Field != "inner" {
+		t.Errorf("details = %v, want one violation of inner", st.Details())
+	}
+
+	valid := message(t, "penumbra.core.keys.v1.Address", `{"altBech32m": "`+validAddress+`"}`)
+	if err := conn.Invoke(context.Background(), "/test.Echo/Echo", valid, dynamicpb.NewMessage(md)); err != nil || !called {
+		t.Errorf("Invoke with a valid request = %v", err)
+	}
+}
@@ -0,0 +1,68 @@
+// Package vtcodec provides a protobuf codec that uses the MarshalVT and
+// UnmarshalVT methods generated by vtprotobuf, so that grpc-go and connect-go
+// skip the reflection-based codec for Penumbra messages.
+//
+// Generating the vtprotobuf methods has no effect on its own: both frameworks
+// keep using proto.Marshal until the codec is replaced. For grpc-go, register
+// it once at startup:
+//
+//	encoding.RegisterCodec(vtcodec.Codec{})
+//
+// For connect-go, pass it to every handler and client:
+//
+//	connect.WithCodec(vtcodec.Codec{})
+//
+// Unlike github.com/planetscale/vtprotobuf/codec/grpc, the codec falls back to
+// proto.Marshal for messages without vtprotobuf methods, such as the health and
+// reflection services registered on the same server.
+package vtcodec
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// Name is the content subtype of the codec. It replaces the default "proto"
+// codec in both grpc-go and connect-go.
+const Name = "proto"
+
+type vtMessage interface {
+	MarshalVT() ([]byte, error)
+	UnmarshalVT([]byte) error
+}
+
+// Codec implements both encoding.Codec from grpc-go and connect.Codec from
+// connect-go.
+type Codec struct{}
+
+// Name returns Name.
+func (Codec) Name() string {
+	return Name
+}
+
+// Marshal encodes v with MarshalVT if it has one, and with proto.Marshal
+// otherwise.
+func (Codec) Marshal(v any) ([]byte, error) {
+	switch m := v.(type) {
+	case vtMessage:
+		return m.MarshalVT()
+	case proto.Message:
+		return proto.Marshal(m)
+	default:
+		return nil, fmt.Errorf("vtcodec: cannot marshal %T: not a proto.Message", v)
+	}
+}
+
+// Unmarshal decodes data into v with UnmarshalVT if it has one, and with
+// proto.Unmarshal otherwise.
+func (Codec) Unmarshal(data []byte, v any) error {
+	switch m := v.(type) {
+	case vtMessage:
+		return m.UnmarshalVT(data)
+	case proto.Message:
+		return proto.Unmarshal(data, m)
+	default:
+		return fmt.Errorf("vtcodec: cannot unmarshal into %T: not a proto.Message", v)
+	}
+}
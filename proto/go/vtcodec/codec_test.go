@@ -0,0 +1,70 @@
+package vtcodec
+
+import (
+	"bytes"
+	"testing"
+
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+var _ encoding.Codec = Codec{}
+
+// fakeVT records which path the codec took.
+type fakeVT struct {
+	data []byte
+}
+
+func (f *fakeVT) MarshalVT() ([]byte, error) { return []byte("vt:" + string(f.data)), nil }
+
+func (f *fakeVT) UnmarshalVT(b []byte) error {
+	f.data = append([]byte("vt:"), b...)
+	return nil
+}
+
+func TestCodecUsesVTMethods(t *testing.T) {
+	var c Codec
+	b, err := c.Marshal(&fakeVT{data: []byte("x")})
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	if !bytes.Equal(b, []byte("vt:x")) {
+		t.Fatalf("Marshal = %q, want %q", b, "vt:x")
+	}
+
+	var f fakeVT
+	if err := c.Unmarshal([]byte("y"), &f); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if !bytes.Equal(f.data, []byte("vt:y")) {
+		t.Fatalf("Unmarshal = %q, want %q", f.data, "vt:y")
+	}
+}
+
+func TestCodecFallsBackToProto(t *testing.T) {
+	var c Codec
+	in := wrapperspb.String("penumbra")
+	b, err := c.Marshal(in)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	out := new(wrapperspb.StringValue)
+	if err := c.Unmarshal(b, out); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if !proto.Equal(in, out) {
+		t.Fatalf("round trip = %v, want %v", out, in)
+	}
+}
+
+func TestCodecRejectsNonMessages(t *testing.T) {
+	var c Codec
+	if _, err := c.Marshal("not a message"); err == nil {
+		t.Fatal("Marshal(string): want error")
+	}
+	var s string
+	if err := c.Unmarshal(nil, &s); err == nil {
+		t.Fatal("Unmarshal(*string): want error")
+	}
+}